@@ -0,0 +1,207 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Add a device to the configuration file at path, creating it if missing.
+// The file is edited as text, so its comments and formatting are kept.
+func AppendDevice(path string, device DeviceConfiguration) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	updated, err := AppendDeviceYAML(content, device)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, updated, 0o644)
+}
+
+// Add a device at the end of the devices of a YAML configuration
+func AppendDeviceYAML(content []byte, device DeviceConfiguration) ([]byte, error) {
+	var config Configuration
+	err := yaml.Unmarshal(content, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range config.Devices {
+		if existing.ID == device.ID {
+			return nil, fmt.Errorf("a device with id '%s' already exists", device.ID)
+		}
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(content, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = []string{}
+	}
+
+	var updated []string
+
+	devices, key := findDevices(&document)
+	switch {
+	case devices == nil:
+		// No devices yet, add the list at the end
+		block, err := deviceBlock(device, "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		updated = append(lines, "devices:")
+		updated = append(updated, block...)
+	case devices.Kind == yaml.ScalarNode && devices.Tag == "!!null":
+		// An empty list, add the device right after its key
+		block, err := deviceBlock(device, "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		updated = insertLines(lines, key.Line, block)
+	case devices.Kind == yaml.SequenceNode && devices.Style&yaml.FlowStyle == 0:
+		indent := "  "
+		if len(devices.Content) > 0 {
+			indent = strings.Repeat(" ", devices.Column-1)
+		}
+
+		block, err := deviceBlock(device, indent)
+		if err != nil {
+			return nil, err
+		}
+
+		updated = insertLines(lines, devicesEnd(&document, key, lines), block)
+	default:
+		return nil, errors.New("devices must be a block list to add a device to it")
+	}
+
+	result := []byte(strings.Join(updated, "\n") + "\n")
+
+	// Make sure the device has been added where intended
+	var check Configuration
+	err = yaml.Unmarshal(result, &check)
+	if err != nil || len(check.Devices) != len(config.Devices)+1 || check.Devices[len(check.Devices)-1].ID != device.ID {
+		return nil, errors.New("cannot add the device to the configuration")
+	}
+
+	return result, nil
+}
+
+// Find the devices value in a configuration document, with its key
+func findDevices(document *yaml.Node) (*yaml.Node, *yaml.Node) {
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "devices" {
+			return root.Content[i+1], root.Content[i]
+		}
+	}
+
+	return nil, nil
+}
+
+// The line after the last device, before the next top level key and the
+// comments and blank lines above it
+func devicesEnd(document *yaml.Node, key *yaml.Node, lines []string) int {
+	root := document.Content[0]
+
+	end := len(lines)
+	for i := 0; i+2 < len(root.Content); i += 2 {
+		if root.Content[i] == key {
+			end = root.Content[i+2].Line - 1
+			break
+		}
+	}
+
+	for end > 0 {
+		line := lines[end-1]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !(strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(line, " ")) {
+			break
+		}
+
+		end--
+	}
+
+	return end
+}
+
+// Insert lines after the first n ones
+func insertLines(lines []string, n int, inserted []string) []string {
+	result := append([]string{}, lines[:n]...)
+	result = append(result, inserted...)
+
+	return append(result, lines[n:]...)
+}
+
+// Render a device as a list item indented by indent
+func deviceBlock(device DeviceConfiguration, indent string) ([]string, error) {
+	var node yaml.Node
+	err := node.Encode(device)
+	if err != nil {
+		return nil, err
+	}
+
+	// USB ids are written in hexadecimal, as in the configuration template
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case "vid", "pid":
+			id, _ := strconv.ParseUint(node.Content[i+1].Value, 10, 16)
+			node.Content[i+1].Value = fmt.Sprintf("0x%04X", id)
+		}
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+
+	err = encoder.Encode(&node)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	for i, line := range lines {
+		if i == 0 {
+			lines[i] = indent + "- " + line
+		} else {
+			lines[i] = indent + "  " + line
+		}
+	}
+
+	return lines, nil
+}
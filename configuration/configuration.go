@@ -23,11 +23,92 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+type ValidationConfiguration struct {
+	Checks  []string `yaml:"checks"`
+	Lengths []int    `yaml:"lengths"`
+	Action  string   `yaml:"action"`
+}
+
+type TimingConfiguration struct {
+	HumanIntervalMs int     `yaml:"human_interval_ms"`
+	MinIntervalMs   int     `yaml:"min_interval_ms"`
+	LearnScans      int     `yaml:"learn_scans"`
+	Tolerance       float64 `yaml:"tolerance"`
+	Action          string  `yaml:"action"`
+}
+
 type DeviceConfiguration struct {
-	ID            string `yaml:"id"`
-	VID           uint16 `yaml:"vid"`
-	PID           uint16 `yaml:"pid"`
-	FullScanRegex string `yaml:"full_scan_regex"`
+	ID            string `yaml:"id,omitempty"`
+	Type          string `yaml:"type,omitempty"`
+	Source        string `yaml:"source,omitempty"` // Alias of type
+	VID           uint16 `yaml:"vid,omitempty"`
+	PID           uint16 `yaml:"pid,omitempty"`
+	Name          string `yaml:"name,omitempty"`
+	Phys          string `yaml:"phys,omitempty"`
+	Uniq          string `yaml:"uniq,omitempty"`
+	HWIDRegex     string `yaml:"hwid_regex,omitempty"`
+	MatchAll      bool   `yaml:"match_all,omitempty"`
+	IDTemplate    string `yaml:"id_template,omitempty"`
+	FullScanRegex string `yaml:"full_scan_regex,omitempty"`
+	Layout        string `yaml:"layout,omitempty"`
+	AltCodes      string `yaml:"alt_codes,omitempty"`
+	Framing       string `yaml:"framing,omitempty"`
+	Prefix        string `yaml:"prefix,omitempty"`
+	Suffix        string `yaml:"suffix,omitempty"`
+	IdleTimeoutMs int    `yaml:"idle_timeout_ms,omitempty"`
+	IdleAction    string `yaml:"idle_action,omitempty"`
+	MaxLength     int    `yaml:"max_length,omitempty"`
+	Symbology     string `yaml:"symbology,omitempty"`
+	GS1           bool   `yaml:"gs1,omitempty"`
+
+	// Passthrough mode settings (evdev source only)
+	Passthrough          bool `yaml:"passthrough,omitempty"`
+	PassthroughGapMs     int  `yaml:"passthrough_gap_ms,omitempty"`
+	PassthroughMinLength int  `yaml:"passthrough_min_length,omitempty"`
+
+	// Serial source settings
+	Port        string `yaml:"port,omitempty"`
+	Baud        int    `yaml:"baud,omitempty"`
+	DataBits    int    `yaml:"data_bits,omitempty"`
+	Parity      string `yaml:"parity,omitempty"`
+	StopBits    int    `yaml:"stop_bits,omitempty"`
+	ReconnectMs int    `yaml:"reconnect_ms,omitempty"`
+
+	// FIFO, file and replay source settings
+	Path  string  `yaml:"path,omitempty"`
+	Speed float64 `yaml:"speed,omitempty"`
+
+	// Network and HTTP source settings
+	Address string            `yaml:"address,omitempty"`
+	Tokens  map[string]string `yaml:"tokens,omitempty"`
+
+	// Network source settings
+	Network     string            `yaml:"network,omitempty"`
+	Frame       string            `yaml:"frame,omitempty"`
+	Delimiter   string            `yaml:"delimiter,omitempty"`
+	LengthBytes int               `yaml:"length_bytes,omitempty"`
+	RemoteIDs   map[string]string `yaml:"remote_ids,omitempty"`
+
+	// HTTP source settings
+	RateLimit float64 `yaml:"rate_limit,omitempty"`
+	Burst     int     `yaml:"burst,omitempty"`
+
+	// HID POS source settings
+	ReportID uint8 `yaml:"report_id,omitempty"`
+
+	Validation *ValidationConfiguration `yaml:"validation,omitempty"`
+
+	// Keystroke timing analysis, rejecting what is not typed by a scanner
+	Timing *TimingConfiguration `yaml:"timing,omitempty"`
+}
+
+// The type of source the device is read with, empty for the default
+func (config *DeviceConfiguration) SourceType() string {
+	if config.Type != "" {
+		return config.Type
+	}
+
+	return config.Source
 }
 
 type TargetConfiguration struct {
@@ -37,13 +118,22 @@ type TargetConfiguration struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	Stream   string `yaml:"stream"`
+
+	// Keyboard target settings
+	Layout     string `yaml:"layout"`
+	KeyDelayMs int    `yaml:"key_delay_ms"`
+	SuffixKey  string `yaml:"suffix_key"`
+	Template   string `yaml:"template"`
 }
 
 type Configuration struct {
-	ID         string                `yaml:"id"`
-	Devices    []DeviceConfiguration `yaml:"devices"`
-	Target     TargetConfiguration   `yaml:"target"`
-	Hearthbeat map[string]any        `yaml:"hearthbeat"`
+	ID             string                `yaml:"id"`
+	Devices        []DeviceConfiguration `yaml:"devices"`
+	Target         TargetConfiguration   `yaml:"target"`
+	InvalidTarget  *TargetConfiguration  `yaml:"invalid_target"`  // Target for the scans routed away by validation
+	Targets        []TargetConfiguration `yaml:"targets"`         // Additional targets, also sent every valid scan
+	SecurityTarget *TargetConfiguration  `yaml:"security_target"` // Target for the security events raised by the readers
+	Hearthbeat     map[string]any        `yaml:"hearthbeat"`
 }
 
 func LoadConfiguration(path string) (*Configuration, error) {
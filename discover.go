@@ -0,0 +1,139 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"sirafino/go-barcode-relay/reader"
+	"strings"
+	"time"
+)
+
+// How terminators are written in full_scan_regex
+var terminatorPattern = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`)
+
+// The discover command: identify a scanner from a test scan and add its
+// configuration to the configuration file
+func discover(args []string) {
+	logger := logging.GetLogger("DISCOVER")
+
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	configPath := flags.String("config", "config/config.yml", "configuration file to add the device to, - to print it instead")
+	id := flags.String("id", "scanner01", "id of the new device")
+	expected := flags.String("expect", "", "content of the test barcode, used to check the keyboard layout")
+	idleMs := flags.Int("idle-ms", 200, "how long the scanner must be quiet for the scan to be over")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-barcode-relay discover [options]")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if *configPath == "-" {
+		// The configuration is the only thing printed to stdout, so that it
+		// can be redirected to a file
+		logging.SetOutput(os.Stderr)
+	} else {
+		printBanner()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("Scan a test barcode with the scanner to configure, press Ctrl+C to cancel")
+
+	scan, err := reader.DiscoverScan(ctx, time.Duration(*idleMs)*time.Millisecond, 3)
+	if err != nil {
+		logger.Error("Discovery failed: %s", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Scan received from %s (%s, VID %04X, PID %04X)", scan.Device.Name, scan.Device.Path, scan.Device.VID, scan.Device.PID)
+
+	profile, err := reader.InferKeyboardProfile(scan.Events, *expected)
+	if err != nil {
+		logger.Error("Cannot infer the keyboard settings: %s", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Decoded %q with layout %s, terminator %q", profile.Content, profile.Layout.Name, profile.Terminator)
+	if *expected == "" {
+		logger.Info("Pass -expect with the content of the barcode to check the layout")
+	}
+
+	selector := reader.UniqueSelector(scan.Device, scan.Devices)
+
+	matches := 0
+	for _, device := range scan.Devices {
+		if selector.Match(device) {
+			matches++
+		}
+	}
+
+	// The relay would refuse to start with a selector matching several devices
+	if matches > 1 {
+		logger.Error("%d identical devices are connected and cannot be told apart, connect only the scanner to configure", matches)
+		os.Exit(1)
+	}
+
+	device := configuration.DeviceConfiguration{
+		ID:            *id,
+		Type:          reader.SourceEvdev,
+		VID:           selector.VID,
+		PID:           selector.PID,
+		Name:          selector.Name,
+		Phys:          selector.Phys,
+		Uniq:          selector.Uniq,
+		Layout:        profile.Layout.Name,
+		FullScanRegex: `.*?\n`,
+		IdleTimeoutMs: 100,
+	}
+
+	if profile.Terminator != "" {
+		device.FullScanRegex = ".*?" + terminatorPattern.Replace(profile.Terminator)
+	} else {
+		// Without a terminator the end of the scan is only known by waiting
+		device.IdleAction = reader.IdleFlush
+	}
+
+	if *configPath == "-" {
+		block, err := configuration.AppendDeviceYAML(nil, device)
+		if err != nil {
+			logger.Error("Cannot build the configuration: %s", err)
+			os.Exit(1)
+		}
+
+		fmt.Print(string(block))
+		return
+	}
+
+	err = configuration.AppendDevice(*configPath, device)
+	if err != nil {
+		logger.Error("Cannot add the device to %s: %s", *configPath, err)
+		os.Exit(1)
+	}
+
+	logger.Info("Device %s added to %s", *id, *configPath)
+}
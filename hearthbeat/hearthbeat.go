@@ -20,6 +20,7 @@ package hearthbeat
 import (
 	"context"
 	"encoding/json"
+	"sirafino/go-barcode-relay/reader"
 	"time"
 )
 
@@ -36,8 +37,7 @@ type Hearthbeat interface {
 }
 
 func getHearthbeatMessage(relayID string) map[string]any {
-	devices := map[string]any{} // TODO
-	devicesJson, _ := json.Marshal(devices)
+	devicesJson, _ := json.Marshal(reader.GrabbedDevices())
 
 	return map[string]any{
 		"relay":   relayID,
@@ -91,6 +91,11 @@ func (device *Device) Init() error {
 	return nil
 }
 
+// The index of the device, as enumerated by the driver
+func (device *Device) Index() int {
+	return device.index
+}
+
 func (device *Device) GetHWID() (string, error) {
 	err := device.deviceIoControl(interceptionIoctlGetHWID)
 	if err != nil {
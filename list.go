@@ -0,0 +1,96 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sirafino/go-barcode-relay/reader"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The list command: describe the connected input devices. The output is
+// meant for other tools too, so only the listing is written to stdout.
+func list(args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	format := flags.String("format", "table", "output format: table, json or yaml")
+	all := flags.Bool("all", false, "also list the devices which cannot send key events")
+	probe := flags.Bool("probe", false, "also report the devices grabbed by other processes, briefly grabbing each device (keys typed meanwhile are lost)")
+
+	flags.Parse(args)
+
+	if *format != "table" && *format != "json" && *format != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown format '%s' (available: table, json, yaml)\n", *format)
+		os.Exit(2)
+	}
+
+	descriptors, err := reader.DescribeDevices(*probe)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error while reading available devices: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !*all {
+		descriptors = reader.Keyboards(descriptors)
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(descriptors)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		err = encoder.Encode(descriptors)
+	default:
+		err = printDeviceTable(descriptors)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error while listing devices: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func printDeviceTable(descriptors []reader.DeviceDescriptor) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(writer, "PATH\tNAME\tVID\tPID\tBUS\tPHYS\tUNIQ\tGRABBED")
+
+	for _, descriptor := range descriptors {
+		grabbed := "no"
+		if descriptor.Reader != "" {
+			grabbed = descriptor.Reader
+		} else if descriptor.Grabbed {
+			grabbed = "yes"
+		}
+
+		fmt.Fprintln(writer, strings.Join([]string{
+			descriptor.Path, descriptor.Name, descriptor.VID, descriptor.PID,
+			descriptor.Bus, descriptor.Phys, descriptor.Uniq, grabbed,
+		}, "\t"))
+	}
+
+	return writer.Flush()
+}
@@ -18,12 +18,19 @@
 package logging
 
 import (
+	"io"
 	"log"
 	"os"
 )
 
 var logger *log.Logger = log.New(os.Stdout, "", log.LstdFlags)
 
+// Write the logs somewhere else than stdout, e.g. when stdout is read by
+// other tools
+func SetOutput(w io.Writer) {
+	logger.SetOutput(w)
+}
+
 type Logger struct {
 	component string
 }
@@ -22,20 +22,24 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"regexp"
 	"sirafino/go-barcode-relay/configuration"
 	"sirafino/go-barcode-relay/hearthbeat"
 	"sirafino/go-barcode-relay/logging"
 	"sirafino/go-barcode-relay/reader"
 	"sirafino/go-barcode-relay/sender"
 	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const VERSION string = "1.0.0"
 
-func main() {
+// How long the readers have to stop once a SIGINT is received
+const shutdownTimeout = 10 * time.Second
+
+// Print the license notice and the version
+func printBanner() {
 	fmt.Println(
 		"BarcodeRelay (Go) Copyright (C) 2025  Gabriele Serafino",
 		"\nThis program comes with ABSOLUTELY NO WARRANTY.",
@@ -44,27 +48,31 @@ func main() {
 	)
 	fmt.Println()
 
-	logger := logging.GetLogger("APP")
+	logging.GetLogger("APP").Info("BarcodeRelay (Go) - v%s", VERSION)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func main() {
+	// The listing is read by other tools, nothing else may be printed
+	if len(os.Args) > 1 && (os.Args[1] == "list" || os.Args[1] == "--list") {
+		list(os.Args[2:])
+		return
+	}
 
-	logger.Info("BarcodeRelay (Go) - v%s", VERSION)
+	// Prints the banner itself, unless the configuration goes to stdout
+	if len(os.Args) > 1 && os.Args[1] == "discover" {
+		discover(os.Args[2:])
+		return
+	}
 
-	// TODO: make this seriously
-	if len(os.Args) > 1 && os.Args[1] == "--list" {
-		devices, error := reader.ListDevices()
-		if error != nil {
-			fmt.Println("Error while reading available devices")
-			return
-		}
+	printBanner()
 
-		logger.Info("Listing available devices:")
+	logger := logging.GetLogger("APP")
 
-		for _, device := range devices {
-			fmt.Printf("  - %s\n", device)
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	if len(os.Args) > 1 && os.Args[1] == "record" {
+		record(os.Args[2:])
 		return
 	}
 
@@ -77,66 +85,137 @@ func main() {
 		logger.Error("Error while loading configuration file")
 		panic(err)
 	}
-	logger.Info("Configuration file loaded (%d device/s, %d target/s)", len(config.Devices), 1)
-
-	// Keep a list of readers, one for each device to be read
-	readers := make([]*reader.DeviceReader, len(config.Devices))
+	targetsCount := 1 + len(config.Targets)
+	if config.InvalidTarget != nil {
+		targetsCount++
+	}
+	if config.SecurityTarget != nil {
+		targetsCount++
+	}
+	logger.Info("Configuration file loaded (%d device/s, %d target/s)", len(config.Devices), targetsCount)
 
-	// Instantiate each device reader based on the configuration
-	for idx, readerConfig := range config.Devices {
-		readers[idx] = nil
+	// Keep a list of sources, one for each device to be read
+	sources := make([]reader.Source, len(config.Devices))
 
-		regex, err := regexp.Compile(readerConfig.FullScanRegex)
+	// Instantiate the source of each device based on the configuration
+	for idx, deviceConfig := range config.Devices {
+		source, err := reader.NewSource(&deviceConfig)
 		if err != nil {
-			logger.Error("Invalid regex for device (%s)", readerConfig.ID)
+			logger.Error("Invalid configuration for device (%s)", deviceConfig.ID)
 			panic(err)
 		}
 
-		deviceReader := reader.DeviceReader{
-			DeviceID: readerConfig.ID,
-			VID:      readerConfig.VID,
-			PID:      readerConfig.PID,
-			Regex:    regex,
+		if deviceConfig.Validation != nil && deviceConfig.Validation.Action == reader.InvalidRoute && config.InvalidTarget == nil {
+			logger.Error("Invalid validation for device (%s)", deviceConfig.ID)
+			panic(fmt.Errorf("the route action requires an invalid_target"))
 		}
 
-		readers[idx] = &deviceReader
-	}
+		if deviceConfig.Timing != nil && deviceConfig.Timing.Action == reader.InvalidRoute && config.InvalidTarget == nil {
+			logger.Error("Invalid timing for device (%s)", deviceConfig.ID)
+			panic(fmt.Errorf("the route action requires an invalid_target"))
+		}
 
-	// Create sender
-	var s sender.Sender
+		sources[idx] = source
+	}
 
-	switch config.Target.Type {
-	case "redis":
-		s = &sender.RedisStreamSender{
-			Host:     config.Target.Host,
-			Port:     config.Target.Port,
-			Username: config.Target.Username,
-			Password: config.Target.Password,
-			Stream:   config.Target.Stream,
+	// Create a sender for each target receiving the valid scans
+	targets := append([]configuration.TargetConfiguration{config.Target}, config.Targets...)
+	senders := make([]sender.Sender, len(targets))
+	for idx, target := range targets {
+		s, err := newSender(target)
+		if err != nil {
+			logger.Error("Invalid configuration for target (%s)", target.Type)
+			panic(err)
 		}
-	case "dummy":
-		s = &sender.DummySender{}
-	default:
-		s = &sender.DummySender{}
+
+		senders[idx] = s
 	}
 
 	// Create the scans channel
 	scans := make(chan reader.Scan)
 
+	// When an invalid scans target is configured, the scans flagged as
+	// invalid by the readers are split from the others
+	targetScans := scans
+	var invalidSender sender.Sender
+	var invalidScans chan reader.Scan
+
+	if config.InvalidTarget != nil {
+		invalidSender, err = newSender(*config.InvalidTarget)
+		if err != nil {
+			logger.Error("Invalid configuration for invalid_target (%s)", config.InvalidTarget.Type)
+			panic(err)
+		}
+
+		targetScans = make(chan reader.Scan)
+		invalidScans = make(chan reader.Scan)
+
+		go routeScans(scans, targetScans, invalidScans)
+	}
+
+	// With additional targets, each sender gets its own copy of the scans
+	sendersScans := []chan reader.Scan{targetScans}
+	if len(senders) > 1 {
+		sendersScans = make([]chan reader.Scan, len(senders))
+		for idx := range sendersScans {
+			sendersScans[idx] = make(chan reader.Scan, broadcastBuffer)
+		}
+
+		go broadcastScans(targetScans, sendersScans)
+	}
+
+	// Security events are sent to their own target, if any. They are dropped
+	// rather than holding up the reader raising them when the target is slow.
+	var securitySender sender.Sender
+	var securityScans chan reader.Scan
+
+	if config.SecurityTarget != nil {
+		securitySender, err = newSender(*config.SecurityTarget)
+		if err != nil {
+			logger.Error("Invalid configuration for security_target (%s)", config.SecurityTarget.Type)
+			panic(err)
+		}
+
+		securityScans = make(chan reader.Scan, broadcastBuffer)
+
+		reader.OnSecurityEvent(func(event reader.SecurityEvent) {
+			select {
+			case securityScans <- event.Scan():
+			default:
+				logger.Error("Security target is not keeping up, dropping event of %s", event.DeviceID)
+			}
+		})
+	}
+
 	// Create waitgroups for readers and senders
 	var readersWaitGroup sync.WaitGroup
 	var sendersWaitGroup sync.WaitGroup
 
 	// Start all readers
-	for _, reader := range readers {
+	for _, source := range sources {
 		readersWaitGroup.Add(1)
-		go reader.Run(ctx, scans, 1000, &readersWaitGroup)
+		go func() {
+			defer readersWaitGroup.Done()
+			source.Run(ctx, scans)
+		}()
 	}
 	logger.Info("Reader/s started")
 
-	// Start sender
-	sendersWaitGroup.Add(1)
-	go s.Run(scans, config.ID, &sendersWaitGroup)
+	// Start senders
+	for idx, s := range senders {
+		sendersWaitGroup.Add(1)
+		go s.Run(sendersScans[idx], config.ID, &sendersWaitGroup)
+	}
+
+	if invalidSender != nil {
+		sendersWaitGroup.Add(1)
+		go invalidSender.Run(invalidScans, config.ID, &sendersWaitGroup)
+	}
+
+	if securitySender != nil {
+		sendersWaitGroup.Add(1)
+		go securitySender.Run(securityScans, config.ID, &sendersWaitGroup)
+	}
 	logger.Info("Sender/s started")
 
 	// If needed, instantiate hearthbeat routing
@@ -186,9 +265,87 @@ func main() {
 
 	cancel()
 
-	readersWaitGroup.Wait()
+	// Readers release their devices and handle their partial scans before
+	// returning, a reader stuck past the timeout must not keep the relay up
+	readersDone := make(chan struct{})
+	go func() {
+		readersWaitGroup.Wait()
+		close(readersDone)
+	}()
+
+	select {
+	case <-readersDone:
+		logger.Info("Reader/s stopped")
+	case <-time.After(shutdownTimeout):
+		for _, source := range sources {
+			if source.Status() != reader.StatusStopped {
+				logger.Error("Reader %s did not stop in time, exiting anyway", source.ID())
+			}
+		}
+		os.Exit(1)
+	}
 
 	close(scans)
+	if securityScans != nil {
+		close(securityScans)
+	}
 
 	sendersWaitGroup.Wait()
+	logger.Info("Sender/s stopped, bye")
+}
+
+// Create the sender for a target
+func newSender(target configuration.TargetConfiguration) (sender.Sender, error) {
+	switch target.Type {
+	// redis_stream is the name used by the first configurations
+	case "redis", "redis_stream":
+		return &sender.RedisStreamSender{
+			Host:     target.Host,
+			Port:     target.Port,
+			Username: target.Username,
+			Password: target.Password,
+			Stream:   target.Stream,
+		}, nil
+	case "keyboard":
+		return sender.NewKeyboardSender(target)
+	case "dummy":
+		return &sender.DummySender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown target type '%s' (available: redis, keyboard, dummy)", target.Type)
+	}
+}
+
+// Scans waiting for a slow target (e.g. Redis being down) before the other
+// targets stop receiving them too
+const broadcastBuffer = 100
+
+// Send a copy of each scan to every target, closing their channels once the
+// scans channel is closed
+func broadcastScans(scans chan reader.Scan, targets []chan reader.Scan) {
+	defer func() {
+		for _, target := range targets {
+			close(target)
+		}
+	}()
+
+	for scan := range scans {
+		for _, target := range targets {
+			target <- scan
+		}
+	}
+}
+
+// Forward the scans flagged as invalid to their own channel and everything
+// else to the main one, closing both once the scans channel is closed
+func routeScans(scans chan reader.Scan, valid chan reader.Scan, invalid chan reader.Scan) {
+	defer close(valid)
+	defer close(invalid)
+
+	for scan := range scans {
+		if scan.Invalid {
+			invalid <- scan
+		} else {
+			valid <- scan
+		}
+	}
 }
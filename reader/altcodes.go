@@ -0,0 +1,115 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"strconv"
+	"strings"
+)
+
+// How Alt+numpad codes without a leading zero are decoded. Codes with a
+// leading zero are always code page 1252 and codes starting with numpad
+// plus are always hexadecimal Unicode code points, as on Windows.
+const (
+	AltCodesCP437   = "437"
+	AltCodesCP1252  = "1252"
+	AltCodesUnicode = "unicode"
+)
+
+// Upper half of code page 437 (0x80 - 0xFF)
+var cp437 = []rune(
+	"ÇüéâäàåçêëèïîìÄÅ" +
+		"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+		"áíóúñÑªº¿⌐¬½¼¡«»" +
+		"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" +
+		"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+		"αßΓπΣσµτΦΘΩδ∞φε∩" +
+		"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+)
+
+// Range 0x80 - 0x9F of code page 1252, the rest matches Latin-1. Undefined
+// positions are mapped to the matching C1 control character.
+var cp1252 = []rune(
+	"€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f" +
+		"\u0090‘’“”•–—˜™š›œ\u009džŸ",
+)
+
+var numpadDigits = map[uint16]string{
+	KeyKP0: "0",
+	KeyKP1: "1",
+	KeyKP2: "2",
+	KeyKP3: "3",
+	KeyKP4: "4",
+	KeyKP5: "5",
+	KeyKP6: "6",
+	KeyKP7: "7",
+	KeyKP8: "8",
+	KeyKP9: "9",
+}
+
+// Decode the digits typed on the numpad while holding alt into a character.
+//
+// Codes below 32 (and 127) are always decoded as the ASCII control
+// characters, since that is what scanners mean when sending them (e.g. GS in
+// GS1 codes or EOT in PDF417 driver licences).
+func decodeAltCode(digits string, mode string) string {
+	if value, found := strings.CutPrefix(digits, "+"); found {
+		code, err := strconv.ParseUint(value, 16, 32)
+		if err != nil {
+			return ""
+		}
+
+		return string(rune(code))
+	}
+
+	code, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil {
+		return ""
+	}
+
+	if mode == AltCodesUnicode && !strings.HasPrefix(digits, "0") {
+		return string(rune(code))
+	}
+
+	// Code pages wrap around like Windows does
+	code = code % 256
+
+	if code < 0x20 || code == 0x7F {
+		if code == 0 {
+			return ""
+		}
+
+		return string(rune(code))
+	}
+
+	if code < 0x80 {
+		return string(rune(code))
+	}
+
+	if strings.HasPrefix(digits, "0") || mode == AltCodesCP1252 {
+		if code < 0xA0 {
+			return string(cp1252[code-0x80])
+		}
+
+		// Latin-1
+		return string(rune(code))
+	}
+
+	return string(cp437[code-0x80])
+}
@@ -0,0 +1,348 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"regexp"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"time"
+)
+
+// What to do with the buffered characters when the idle timeout expires
+const (
+	IdleDiscard = "discard"
+	IdleFlush   = "flush"
+)
+
+// How the boundaries of a scan are detected
+const (
+	// A scan is complete as soon as the buffer matches the full scan regex
+	FramingRegex = "regex"
+	// A scan is whatever is received between the prefix and the suffix, which
+	// are stripped, so payloads can contain newlines
+	FramingDelimited = "delimited"
+	// Each message received is a whole scan, used by the sources delivering
+	// complete barcodes instead of single characters
+	FramingMessage = "message"
+)
+
+// How the characters read from a device are assembled into scans and how
+// the scans are processed, shared by every kind of reader.
+type ScanOptions struct {
+	Regex       *regexp.Regexp
+	Framing     string // FramingRegex (default), FramingDelimited or FramingMessage
+	Prefix      string
+	Suffix      string
+	IdleTimeout time.Duration  // Zero disables the idle timeout
+	FlushOnIdle bool           // Emit the buffer as a scan on idle instead of discarding it
+	MaxLength   int            // Zero means unlimited
+	Symbology   string         // Symbology detection mode, empty disables it
+	GS1         bool           // Parse GS1 Application Identifiers into scan fields
+	Validator   *Validator     // Nil disables validation
+	Timing      *TimingOptions // Nil disables the timing analysis
+}
+
+// What the sources send to the assembler
+type input struct {
+	text      string    // A character, or a whole scan with message framing
+	symbology string    // Reported by the device along with a whole scan, if any
+	at        time.Time // When the character was typed, zero if only known once received
+	reset     bool      // Drop the buffer instead, e.g. when the device is unplugged
+}
+
+// Collects the characters read from a device into scans
+type assembler struct {
+	ScanOptions
+
+	deviceID string
+	buffer   string
+	logger   *logging.Logger
+
+	// Whether the prefix has been received and the buffer holds a payload
+	inFrame bool
+
+	// The symbology reported by the device for the buffered scan
+	symbology string
+
+	// When each character of the buffer has been typed and the typing
+	// profile of the device, when the timing is analyzed
+	times  []time.Time
+	timing *TimingProfile
+}
+
+// Emit the buffered content as a scan and clear the buffer, returning
+// whether the scan has been sent
+func (a *assembler) emit(scans chan<- Scan) bool {
+	scan := Scan{
+		DeviceID:  a.deviceID,
+		Content:   a.buffer,
+		Symbology: a.symbology,
+		Timestamp: time.Now().Unix(),
+	}
+
+	// The configured detection takes precedence over the reported symbology
+	if a.Symbology != "" {
+		scan.Symbology, scan.Content = DetectSymbology(scan.Content, a.Symbology)
+	}
+
+	if a.GS1 {
+		a.parseGS1(&scan)
+	}
+
+	if a.timing != nil && !a.checkTiming(&scan) {
+		a.reset()
+		return false
+	}
+
+	if a.Validator != nil && !a.Validator.Validate(&scan) {
+		switch a.Validator.Action {
+		case InvalidFlag:
+			scan.SetField("valid", "false")
+		case InvalidRoute:
+			scan.Invalid = true
+		default:
+			a.logger.Error("Dropping invalid scan (%s)", strings.ReplaceAll(scan.Content, "\n", ""))
+			a.reset()
+			return false
+		}
+
+		a.logger.Error("Invalid scan (%s)", strings.ReplaceAll(scan.Content, "\n", ""))
+	}
+
+	a.logger.Info("Read scan (%s)", strings.ReplaceAll(scan.Content, "\n", ""))
+
+	scans <- scan
+	a.reset()
+
+	return true
+}
+
+// Attach the GS1 elements of the scan as "gs1_<AI>" fields
+func (a *assembler) parseGS1(scan *Scan) {
+	elements, err := ParseGS1(scan.Content)
+	if err != nil {
+		a.logger.Error("Invalid GS1 data (%s): %s", strings.ReplaceAll(scan.Content, "\n", ""), err)
+		return
+	}
+
+	for _, element := range elements {
+		scan.SetField("gs1_"+element.AI, element.Value)
+	}
+}
+
+// Check the timing of the keystrokes of a scan, raising a security event
+// when it is suspicious. Returns whether the scan must still be sent.
+func (a *assembler) checkTiming(scan *Scan) bool {
+	intervals := make([]time.Duration, 0, len(a.times))
+	for i := 1; i < len(a.times); i++ {
+		intervals = append(intervals, a.times[i].Sub(a.times[i-1]))
+	}
+
+	kind := a.timing.Analyze(intervals)
+	if kind == "" {
+		return true
+	}
+
+	raiseSecurityEvent(SecurityEvent{
+		DeviceID:  a.deviceID,
+		Kind:      kind,
+		Content:   scan.Content,
+		Intervals: intervals,
+		Profile:   a.timing.Interval(),
+		Timestamp: scan.Timestamp,
+	})
+
+	switch a.Timing.Action {
+	case InvalidFlag:
+		scan.SetField("timing", kind)
+	case InvalidRoute:
+		scan.Invalid = true
+	default:
+		a.logger.Error("Dropping suspicious scan (%s), timing %s: %s ms", strings.ReplaceAll(scan.Content, "\n", ""), kind, formatIntervals(intervals))
+		return false
+	}
+
+	a.logger.Error("Suspicious scan (%s), timing %s: %s ms", strings.ReplaceAll(scan.Content, "\n", ""), kind, formatIntervals(intervals))
+
+	return true
+}
+
+func newAssembler(options ScanOptions, deviceID string, logger *logging.Logger) *assembler {
+	a := &assembler{
+		ScanOptions: options,
+		deviceID:    deviceID,
+		logger:      logger,
+	}
+
+	if options.Timing != nil {
+		a.timing = NewTimingProfile(*options.Timing)
+	}
+
+	return a
+}
+
+func (a *assembler) reset() {
+	a.buffer = ""
+	a.inFrame = false
+	a.symbology = ""
+	a.times = a.times[:0]
+}
+
+// Only keep the times of the last characters, after the beginning
+// of the buffer has been dropped
+func (a *assembler) keepTimes(characters int) {
+	if characters < len(a.times) {
+		a.times = a.times[len(a.times)-characters:]
+	}
+}
+
+// Append a character typed at the given time to the buffer, emitting a scan
+// when the buffer matches the full scan regex or the frame suffix
+func (a *assembler) push(character string, at time.Time, scans chan<- Scan) {
+	a.buffer += character
+	if a.timing != nil {
+		a.times = append(a.times, at)
+	}
+
+	if a.Framing == FramingMessage {
+		a.emit(scans)
+	} else if a.Framing == FramingDelimited {
+		a.pushFramed(scans)
+	} else if a.Regex.Match([]byte(a.buffer)) {
+		// The buffer matches the full_scan_regex
+		a.emit(scans)
+	}
+
+	if a.MaxLength > 0 && len([]rune(a.buffer)) > a.MaxLength {
+		a.logger.Error("Scan buffer exceeded %d characters, discarding it", a.MaxLength)
+		a.reset()
+	}
+}
+
+// Framing of a buffer that just received a new character, waits for the
+// prefix (if any) and then for the suffix, stripping both
+func (a *assembler) pushFramed(scans chan<- Scan) {
+	if !a.inFrame {
+		if a.Prefix == "" {
+			a.inFrame = true
+		} else if strings.HasSuffix(a.buffer, a.Prefix) {
+			// Anything before the prefix is noise
+			a.inFrame = true
+			a.buffer = ""
+			a.keepTimes(0)
+			return
+		} else {
+			// Only keep what could still be the beginning of the prefix, in
+			// characters so that none is cut in half
+			buffer := []rune(a.buffer)
+			if keep := len([]rune(a.Prefix)) - 1; len(buffer) > keep {
+				a.buffer = string(buffer[len(buffer)-keep:])
+				a.keepTimes(keep)
+			}
+			return
+		}
+	}
+
+	if strings.HasSuffix(a.buffer, a.Suffix) {
+		a.buffer = strings.TrimSuffix(a.buffer, a.Suffix)
+		a.emit(scans)
+	}
+}
+
+// Handle the expiration of the idle timeout, no key has been received for a
+// while so the buffered content is either a whole scan or a leftover
+func (a *assembler) idle(scans chan<- Scan) {
+	if a.buffer == "" && !a.inFrame {
+		return
+	}
+
+	if a.Framing == FramingDelimited && !a.inFrame {
+		// Leftovers of something that was not a frame
+		a.reset()
+		return
+	}
+
+	if a.FlushOnIdle && a.buffer != "" {
+		a.emit(scans)
+		return
+	}
+
+	a.discard()
+}
+
+// Drop the buffered characters, which cannot be part of the next scan
+func (a *assembler) discard() {
+	if a.buffer != "" {
+		a.logger.Info("Discarding incomplete scan (%s)", strings.ReplaceAll(a.buffer, "\n", ""))
+	}
+
+	a.reset()
+}
+
+// Run read in its own goroutine and assemble the characters it sends into
+// scans until it returns, which it must do once the context is done. The
+// characters left in the buffer are then handled as on idle timeout, so
+// nothing is left running or pending when a source stops.
+func (a *assembler) run(ctx context.Context, read func(context.Context, chan input), scans chan<- Scan) {
+	characters := make(chan input, 1)
+
+	go func() {
+		defer close(characters)
+		read(ctx, characters)
+	}()
+
+	// The idle timer only runs while there is something in the buffer
+	idleTimer := time.NewTimer(time.Hour)
+	idleTimer.Stop()
+	defer idleTimer.Stop()
+
+	for {
+		select {
+		case in, ok := <-characters:
+			if !ok {
+				a.logger.Info("Stopping device reader: %s", a.deviceID)
+				a.idle(scans)
+				return
+			}
+
+			idleTimer.Stop()
+
+			if in.reset {
+				a.discard()
+				continue
+			}
+
+			if in.symbology != "" {
+				a.symbology = in.symbology
+			}
+			at := in.at
+			if at.IsZero() {
+				at = time.Now()
+			}
+			a.push(in.text, at, scans)
+
+			if a.IdleTimeout > 0 && (a.buffer != "" || a.inFrame) {
+				idleTimer.Reset(a.IdleTimeout)
+			}
+		case <-idleTimer.C:
+			a.idle(scans)
+		}
+	}
+}
@@ -17,43 +17,181 @@
 
 package reader
 
-var CharMap = map[uint16]string{
-	2:  "1",
-	3:  "2",
-	4:  "3",
-	5:  "4",
-	6:  "5",
-	7:  "6",
-	8:  "7",
-	9:  "8",
-	10: "9",
-	11: "0",
-	16: "Q",
-	17: "W",
-	18: "E",
-	19: "R",
-	20: "T",
-	21: "Y",
-	22: "U",
-	23: "I",
-	24: "O",
-	25: "P",
-	28: "\n", // Enter
-	30: "A",
-	31: "S",
-	32: "D",
-	33: "F",
-	34: "G",
-	35: "H",
-	36: "J",
-	37: "K",
-	38: "L",
-	42: "", // Shift
-	44: "Z",
-	45: "X",
-	46: "C",
-	47: "V",
-	48: "B",
-	49: "N",
-	50: "M",
+// Key codes, as defined in linux/input-event-codes.h.
+//
+// These also match the scan code set 1 codes received from the Interception
+// driver on Windows, apart from the E0 extended keys (see reader_windows.go).
+const (
+	KeyEsc        uint16 = 1
+	KeyMinus      uint16 = 12
+	KeyEqual      uint16 = 13
+	KeyBackspace  uint16 = 14
+	KeyTab        uint16 = 15
+	KeyLeftBrace  uint16 = 26
+	KeyRightBrace uint16 = 27
+	KeyEnter      uint16 = 28
+	KeyLeftCtrl   uint16 = 29
+	KeySemicolon  uint16 = 39
+	KeyApostrophe uint16 = 40
+	KeyGrave      uint16 = 41
+	KeyLeftShift  uint16 = 42
+	KeyBackslash  uint16 = 43
+	KeyComma      uint16 = 51
+	KeyDot        uint16 = 52
+	KeySlash      uint16 = 53
+	KeyRightShift uint16 = 54
+	KeyKPAsterisk uint16 = 55
+	KeyLeftAlt    uint16 = 56
+	KeySpace      uint16 = 57
+	KeyCapsLock   uint16 = 58
+	KeyNumLock    uint16 = 69
+	KeyKP7        uint16 = 71
+	KeyKP8        uint16 = 72
+	KeyKP9        uint16 = 73
+	KeyKPMinus    uint16 = 74
+	KeyKP4        uint16 = 75
+	KeyKP5        uint16 = 76
+	KeyKP6        uint16 = 77
+	KeyKPPlus     uint16 = 78
+	KeyKP1        uint16 = 79
+	KeyKP2        uint16 = 80
+	KeyKP3        uint16 = 81
+	KeyKP0        uint16 = 82
+	KeyKPDot      uint16 = 83
+	Key102nd      uint16 = 86 // The extra key next to left shift on ISO keyboards
+	KeyKPEnter    uint16 = 96
+	KeyRightCtrl  uint16 = 97
+	KeyKPSlash    uint16 = 98
+	KeyRightAlt   uint16 = 100
+	KeyKPEqual    uint16 = 117
+	KeyKPComma    uint16 = 121
+)
+
+// The characters produced by a single key of a keyboard layout.
+//
+// Dead keys produce one of the Dead* combining marks, which are composed
+// with the following character by the Keyboard.
+type KeyChars struct {
+	Normal       string
+	Shifted      string
+	AltGr        string
+	ShiftedAltGr string
+
+	// Whether caps lock inverts the shift state for this key (letters only)
+	Letter bool
+}
+
+// A keyboard layout, maps each key code to the characters it produces
+type Layout struct {
+	Name string
+	Keys map[uint16]KeyChars
+
+	// Whether right alt acts as AltGr, set when any key has an AltGr level
+	AltGr bool
+}
+
+// Helper to build the KeyChars for a letter key
+func letter(lower string, upper string) KeyChars {
+	return KeyChars{Normal: lower, Shifted: upper, Letter: true}
 }
+
+// Numpad keys are decoded as if num lock was always on, which is what
+// scanners emitting numpad keys expect.
+var numpadKeys = map[uint16]KeyChars{
+	KeyKPAsterisk: {Normal: "*", Shifted: "*"},
+	KeyKP7:        {Normal: "7", Shifted: "7"},
+	KeyKP8:        {Normal: "8", Shifted: "8"},
+	KeyKP9:        {Normal: "9", Shifted: "9"},
+	KeyKPMinus:    {Normal: "-", Shifted: "-"},
+	KeyKP4:        {Normal: "4", Shifted: "4"},
+	KeyKP5:        {Normal: "5", Shifted: "5"},
+	KeyKP6:        {Normal: "6", Shifted: "6"},
+	KeyKPPlus:     {Normal: "+", Shifted: "+"},
+	KeyKP1:        {Normal: "1", Shifted: "1"},
+	KeyKP2:        {Normal: "2", Shifted: "2"},
+	KeyKP3:        {Normal: "3", Shifted: "3"},
+	KeyKP0:        {Normal: "0", Shifted: "0"},
+	KeyKPDot:      {Normal: ".", Shifted: "."},
+	KeyKPEnter:    {Normal: "\n", Shifted: "\n"},
+	KeyKPSlash:    {Normal: "/", Shifted: "/"},
+	KeyKPEqual:    {Normal: "=", Shifted: "="},
+	KeyKPComma:    {Normal: ",", Shifted: ","},
+}
+
+// Build a layout from its own keys, adding the keys shared by every layout
+// (numpad, enter, tab and space)
+func newLayout(name string, keys map[uint16]KeyChars) *Layout {
+	layout := Layout{
+		Name: name,
+		Keys: map[uint16]KeyChars{
+			KeyEnter: {Normal: "\n", Shifted: "\n"},
+			KeyTab:   {Normal: "\t", Shifted: "\t"},
+			KeySpace: {Normal: " ", Shifted: " "},
+		},
+	}
+
+	for code, chars := range numpadKeys {
+		layout.Keys[code] = chars
+	}
+
+	for code, chars := range keys {
+		layout.Keys[code] = chars
+
+		if chars.AltGr != "" || chars.ShiftedAltGr != "" {
+			layout.AltGr = true
+		}
+	}
+
+	return &layout
+}
+
+// US 104-key layout
+var LayoutUS = newLayout("us", map[uint16]KeyChars{
+	KeyGrave:      {Normal: "`", Shifted: "~"},
+	2:             {Normal: "1", Shifted: "!"},
+	3:             {Normal: "2", Shifted: "@"},
+	4:             {Normal: "3", Shifted: "#"},
+	5:             {Normal: "4", Shifted: "$"},
+	6:             {Normal: "5", Shifted: "%"},
+	7:             {Normal: "6", Shifted: "^"},
+	8:             {Normal: "7", Shifted: "&"},
+	9:             {Normal: "8", Shifted: "*"},
+	10:            {Normal: "9", Shifted: "("},
+	11:            {Normal: "0", Shifted: ")"},
+	KeyMinus:      {Normal: "-", Shifted: "_"},
+	KeyEqual:      {Normal: "=", Shifted: "+"},
+	16:            letter("q", "Q"),
+	17:            letter("w", "W"),
+	18:            letter("e", "E"),
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("y", "Y"),
+	22:            letter("u", "U"),
+	23:            letter("i", "I"),
+	24:            letter("o", "O"),
+	25:            letter("p", "P"),
+	KeyLeftBrace:  {Normal: "[", Shifted: "{"},
+	KeyRightBrace: {Normal: "]", Shifted: "}"},
+	KeyBackslash:  {Normal: "\\", Shifted: "|"},
+	30:            letter("a", "A"),
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  {Normal: ";", Shifted: ":"},
+	KeyApostrophe: {Normal: "'", Shifted: "\""},
+	44:            letter("z", "Z"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            letter("m", "M"),
+	KeyComma:      {Normal: ",", Shifted: "<"},
+	KeyDot:        {Normal: ".", Shifted: ">"},
+	KeySlash:      {Normal: "/", Shifted: "?"},
+})
@@ -0,0 +1,249 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDeviceNotFound  = errors.New("notfound")
+	ErrAmbiguousDevice = errors.New("more than one device matches the selector")
+)
+
+// Identification data of an input device
+type DeviceInfo struct {
+	Path string
+	Name string
+	Phys string // Physical location, e.g. the USB port path
+	Uniq string // Unique identifier, usually the serial number
+	VID  uint16
+	PID  uint16
+	Bus  uint16 // Bus type (e.g. 0x03 for USB), zero when not known
+
+	// Whether the device can type, as opposed to the mouse or consumer
+	// control devices many scanners expose next to their keyboard
+	Keyboard bool
+
+	// A single string combining all the above, matched by hwid_regex. On
+	// Windows this is the hardware id reported by the Interception driver.
+	HWID string
+}
+
+// Description of an input device, as listed by the list command and sent
+// with the heartbeat
+type DeviceDescriptor struct {
+	Path         string   `json:"path" yaml:"path"`
+	Name         string   `json:"name" yaml:"name"`
+	Phys         string   `json:"phys" yaml:"phys"`
+	Uniq         string   `json:"uniq" yaml:"uniq"`
+	VID          string   `json:"vid" yaml:"vid"` // Hexadecimal, as written in the configuration (0x0C2E)
+	PID          string   `json:"pid" yaml:"pid"`
+	Bus          string   `json:"bus" yaml:"bus"`
+	Capabilities []string `json:"capabilities" yaml:"capabilities"` // Event types, e.g. EV_KEY
+	Grabbed      bool     `json:"grabbed" yaml:"grabbed"`
+	Reader       string   `json:"reader,omitempty" yaml:"reader,omitempty"` // Id of the reader of this relay grabbing it
+}
+
+// Whether the device can send key events
+func (descriptor *DeviceDescriptor) IsKeyboard() bool {
+	return slices.Contains(descriptor.Capabilities, "EV_KEY")
+}
+
+// The devices which can send key events
+func Keyboards(descriptors []DeviceDescriptor) []DeviceDescriptor {
+	keyboards := []DeviceDescriptor{}
+	for _, descriptor := range descriptors {
+		if descriptor.IsKeyboard() {
+			keyboards = append(keyboards, descriptor)
+		}
+	}
+
+	return keyboards
+}
+
+var busNames = map[uint16]string{
+	0x01: "pci",
+	0x03: "usb",
+	0x05: "bluetooth",
+	0x06: "virtual",
+	0x11: "i8042",
+	0x13: "rs232",
+	0x18: "i2c",
+	0x19: "host",
+	0x1C: "spi",
+}
+
+func newDeviceDescriptor(info *DeviceInfo, capabilities []string) DeviceDescriptor {
+	bus := busNames[info.Bus]
+	if bus == "" && info.Bus != 0 {
+		bus = fmt.Sprintf("0x%02X", info.Bus)
+	}
+
+	reader := grabbedBy(info.Path)
+
+	return DeviceDescriptor{
+		Path:         info.Path,
+		Name:         info.Name,
+		Phys:         info.Phys,
+		Uniq:         info.Uniq,
+		VID:          fmt.Sprintf("0x%04X", info.VID),
+		PID:          fmt.Sprintf("0x%04X", info.PID),
+		Bus:          bus,
+		Capabilities: capabilities,
+		Grabbed:      reader != "",
+		Reader:       reader,
+	}
+}
+
+// The devices grabbed by the readers of this relay, described when they are
+// grabbed, by path
+var grabs = struct {
+	mutex   sync.Mutex
+	devices map[string]DeviceDescriptor
+}{devices: map[string]DeviceDescriptor{}}
+
+func setGrabbed(descriptor DeviceDescriptor, readerID string) {
+	grabs.mutex.Lock()
+	defer grabs.mutex.Unlock()
+
+	descriptor.Grabbed = true
+	descriptor.Reader = readerID
+	grabs.devices[descriptor.Path] = descriptor
+}
+
+func releaseGrabbed(path string) {
+	grabs.mutex.Lock()
+	defer grabs.mutex.Unlock()
+
+	delete(grabs.devices, path)
+}
+
+// The id of the reader grabbing the device, empty if none
+func grabbedBy(path string) string {
+	grabs.mutex.Lock()
+	defer grabs.mutex.Unlock()
+
+	return grabs.devices[path].Reader
+}
+
+// The devices read by this relay, sorted by path. Nothing is opened, they
+// are described as they were when grabbed.
+func GrabbedDevices() []DeviceDescriptor {
+	grabs.mutex.Lock()
+	defer grabs.mutex.Unlock()
+
+	descriptors := make([]DeviceDescriptor, 0, len(grabs.devices))
+	for _, descriptor := range grabs.devices {
+		descriptors = append(descriptors, descriptor)
+	}
+
+	slices.SortFunc(descriptors, func(a, b DeviceDescriptor) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return descriptors
+}
+
+// Selects the device to read from, all the non-empty criteria must match
+type DeviceSelector struct {
+	Path      string
+	VID       uint16
+	PID       uint16
+	Name      string
+	Phys      string
+	Uniq      string
+	HWIDRegex *regexp.Regexp
+}
+
+// Whether no criteria has been set, such a selector would match any device
+func (selector *DeviceSelector) IsEmpty() bool {
+	return selector.Path == "" && selector.VID == 0 && selector.PID == 0 &&
+		selector.Name == "" && selector.Phys == "" && selector.Uniq == "" &&
+		selector.HWIDRegex == nil
+}
+
+func (selector *DeviceSelector) Match(info *DeviceInfo) bool {
+	if selector.Path != "" && selector.Path != info.Path {
+		return false
+	}
+
+	if selector.VID != 0 && selector.VID != info.VID {
+		return false
+	}
+
+	if selector.PID != 0 && selector.PID != info.PID {
+		return false
+	}
+
+	if selector.Name != "" && selector.Name != info.Name {
+		return false
+	}
+
+	if selector.Phys != "" && selector.Phys != info.Phys {
+		return false
+	}
+
+	if selector.Uniq != "" && selector.Uniq != info.Uniq {
+		return false
+	}
+
+	if selector.HWIDRegex != nil && !selector.HWIDRegex.MatchString(info.HWID) {
+		return false
+	}
+
+	return true
+}
+
+// The keyboards among the devices matching the selector
+func MatchingKeyboards(selector *DeviceSelector, devices []*DeviceInfo) []*DeviceInfo {
+	matches := []*DeviceInfo{}
+	for _, info := range devices {
+		if info.Keyboard && selector.Match(info) {
+			matches = append(matches, info)
+		}
+	}
+
+	return matches
+}
+
+// Select the single keyboard matching the selector, failing if there is
+// none or more than one
+func SelectDevice(selector *DeviceSelector, devices []*DeviceInfo) (*DeviceInfo, error) {
+	matches := MatchingKeyboards(selector, devices)
+
+	if len(matches) == 0 {
+		return nil, ErrDeviceNotFound
+	}
+
+	if len(matches) > 1 {
+		paths := make([]string, len(matches))
+		for i, match := range matches {
+			paths[i] = match.Path
+		}
+
+		return nil, fmt.Errorf("%w (%s)", ErrAmbiguousDevice, strings.Join(paths, ", "))
+	}
+
+	return matches[0], nil
+}
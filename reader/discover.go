@@ -0,0 +1,155 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A test scan captured by discovery, with the device it came from
+type DiscoveredScan struct {
+	Device *DeviceInfo
+	Events []InputEvent
+
+	// Every device connected during discovery, to build a selector that
+	// matches the scanner only
+	Devices []*DeviceInfo
+}
+
+// The keyboard settings of a scanner, inferred from a test scan
+type KeyboardProfile struct {
+	Layout *Layout
+
+	// The scan decoded with the layout, terminator excluded
+	Content string
+
+	// The characters the scanner ends each scan with, empty if none
+	Terminator string
+}
+
+// Characters scanners end their scans with
+const terminatorCharacters = "\r\n\t"
+
+// Infer the layout and terminator of a scanner from the raw events of a test
+// scan. When the content of the scanned barcode is known, the first layout
+// decoding it is chosen, otherwise the first layout decoding every keystroke
+// (us before the others).
+func InferKeyboardProfile(events []InputEvent, expected string) (*KeyboardProfile, error) {
+	candidates := []*Layout{LayoutUS}
+	for _, name := range LayoutNames() {
+		if name != LayoutUS.Name {
+			layout, _ := GetLayout(name)
+			candidates = append(candidates, layout)
+		}
+	}
+
+	for _, layout := range candidates {
+		decoded, unknown := decodeEvents(events, layout)
+		if decoded == "" {
+			continue
+		}
+
+		content := strings.TrimRight(decoded, terminatorCharacters)
+		profile := &KeyboardProfile{
+			Layout:     layout,
+			Content:    content,
+			Terminator: decoded[len(content):],
+		}
+
+		if expected != "" && content == expected {
+			return profile, nil
+		}
+
+		if expected == "" && unknown == 0 {
+			return profile, nil
+		}
+	}
+
+	if expected != "" {
+		decoded, _ := decodeEvents(events, LayoutUS)
+		return nil, fmt.Errorf("the scan (%q as us) does not decode to %q with any layout", decoded, expected)
+	}
+
+	return nil, errors.New("the scan cannot be decoded with any layout")
+}
+
+// Decode the events with a layout, also returning how many keys were pressed
+// which the layout does not know
+func decodeEvents(events []InputEvent, layout *Layout) (string, int) {
+	keyboard := Keyboard{Layout: layout}
+
+	var decoded strings.Builder
+	unknown := 0
+
+	for _, event := range events {
+		decoded.WriteString(keyboard.HandleEvent(event.Type, event.Code, event.Value))
+
+		if event.Type == EventKey && event.Value == 1 && !isModifier(event.Code) {
+			if _, ok := layout.Keys[event.Code]; !ok {
+				unknown++
+			}
+		}
+	}
+
+	return decoded.String(), unknown
+}
+
+func isModifier(code uint16) bool {
+	switch code {
+	case KeyLeftShift, KeyRightShift, KeyLeftCtrl, KeyRightCtrl, KeyLeftAlt, KeyRightAlt, KeyCapsLock:
+		return true
+	}
+
+	return false
+}
+
+// Build a selector matching the device only, using the fewest criteria
+// among vid/pid, name, physical location and unique id. Identical devices
+// without a unique id cannot be told apart, the selector matches them all.
+func UniqueSelector(device *DeviceInfo, devices []*DeviceInfo) DeviceSelector {
+	selector := DeviceSelector{VID: device.VID, PID: device.PID}
+
+	refinements := []func(){
+		func() { selector.Name = device.Name },
+		func() { selector.Phys = device.Phys },
+		func() { selector.Uniq = device.Uniq },
+	}
+
+	for _, refine := range refinements {
+		if countMatches(&selector, devices) <= 1 {
+			break
+		}
+
+		refine()
+	}
+
+	return selector
+}
+
+func countMatches(selector *DeviceSelector, devices []*DeviceInfo) int {
+	count := 0
+	for _, device := range devices {
+		if selector.Match(device) {
+			count++
+		}
+	}
+
+	return count
+}
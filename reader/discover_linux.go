@@ -0,0 +1,123 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/holoplot/go-evdev"
+)
+
+// An event read from one of the devices watched by discovery
+type discoveryEvent struct {
+	device *DeviceInfo
+	event  InputEvent
+}
+
+// Wait for a test scan on any keyboard device and return it together with
+// the device it came from. The scan is over once the device has been quiet
+// for the idle time, bursts of fewer than minKeys key presses (someone
+// touching a keyboard) are ignored. Devices are not grabbed.
+func DiscoverScan(ctx context.Context, idle time.Duration, minKeys int) (*DiscoveredScan, error) {
+	devices, err := ListMatchingDevices(&DeviceSelector{})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan discoveryEvent)
+	watched := 0
+
+	for _, info := range devices {
+		device, err := evdev.Open(info.Path)
+		if err != nil {
+			continue
+		}
+
+		watched++
+
+		// Closing the device unblocks the pending read
+		context.AfterFunc(ctx, func() { device.Close() })
+
+		go func() {
+			source := &evdevDevice{device: device}
+
+			for {
+				event, err := source.ReadEvent()
+				if err != nil {
+					return
+				}
+
+				select {
+				case events <- discoveryEvent{device: info, event: event}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	if watched == 0 {
+		return nil, errors.New("no keyboard device can be read, check the permissions of /dev/input")
+	}
+
+	var current *DeviceInfo
+	var captured []InputEvent
+	keys := 0
+
+	timer := time.NewTimer(idle)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case received := <-events:
+			isPress := received.event.Type == EventKey && received.event.Value == 1
+
+			if current == nil {
+				if !isPress {
+					continue
+				}
+
+				current = received.device
+			}
+
+			if received.device != current {
+				continue
+			}
+
+			captured = append(captured, received.event)
+			if isPress {
+				keys++
+			}
+
+			timer.Reset(idle)
+		case <-timer.C:
+			if keys >= minKeys {
+				return &DiscoveredScan{Device: current, Events: captured, Devices: devices}, nil
+			}
+
+			current, captured, keys = nil, nil, 0
+		}
+	}
+}
@@ -0,0 +1,65 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+)
+
+// A raw Linux input event, as read from evdev or stored in a recording
+type InputEvent struct {
+	Time  int64  `json:"time"` // Microseconds since the epoch
+	Type  uint16 `json:"type"`
+	Code  uint16 `json:"code"`
+	Value int32  `json:"value"`
+}
+
+// An open input device
+type EventDevice interface {
+	// Take exclusive access of the device, so that its keystrokes do not
+	// reach anything else
+	Grab() error
+	// Wait for the next event, failing once the device is gone or closed
+	ReadEvent() (InputEvent, error)
+	// The device node, e.g. /dev/input/event3
+	Path() string
+	Close() error
+}
+
+// Where a DeviceReader gets its input devices from, evdev by default. Tests
+// replace it to feed scripted events.
+type EventSource interface {
+	// Open the single device matching the selector, ErrDeviceNotFound if
+	// there is none
+	Open(selector *DeviceSelector) (EventDevice, error)
+	// Return a channel signaled when devices may have been connected or
+	// disconnected, until the context is done
+	Watch(ctx context.Context) <-chan struct{}
+	// Create a virtual keyboard able to send the events of the device, used
+	// in passthrough mode
+	Mirror(device EventDevice) (EventSink, error)
+	// List the keyboards matching the selector, used by the spawner
+	List(selector *DeviceSelector) ([]*DeviceInfo, error)
+}
+
+// Where the events which are not part of a scan are sent back to the system
+type EventSink interface {
+	WriteEvent(event InputEvent) error
+	// Remove the virtual keyboard
+	Close() error
+}
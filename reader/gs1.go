@@ -0,0 +1,285 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The GS (group separator) character, sent by scanners in place of FNC1
+const GS = "\x1d"
+
+// Definition of a GS1 Application Identifier
+type gs1AI struct {
+	title   string
+	length  int  // Fixed length of the data, 0 if variable
+	max     int  // Maximum length of variable length data
+	numeric bool // Whether the data can only contain digits
+	date    bool // Whether the data is a YYMMDD date
+}
+
+// GS1 Application Identifiers. Four digits AIs whose last digit is the
+// position of the decimal point are listed with a trailing "n".
+var gs1AIs = map[string]gs1AI{
+	"00":   {title: "SSCC", length: 18, numeric: true},
+	"01":   {title: "GTIN", length: 14, numeric: true},
+	"02":   {title: "CONTENT", length: 14, numeric: true},
+	"10":   {title: "BATCH/LOT", max: 20},
+	"11":   {title: "PROD DATE", length: 6, numeric: true, date: true},
+	"12":   {title: "DUE DATE", length: 6, numeric: true, date: true},
+	"13":   {title: "PACK DATE", length: 6, numeric: true, date: true},
+	"15":   {title: "BEST BEFORE", length: 6, numeric: true, date: true},
+	"16":   {title: "SELL BY", length: 6, numeric: true, date: true},
+	"17":   {title: "USE BY OR EXPIRY", length: 6, numeric: true, date: true},
+	"20":   {title: "VARIANT", length: 2, numeric: true},
+	"21":   {title: "SERIAL", max: 20},
+	"22":   {title: "CPV", max: 20},
+	"235":  {title: "TPX", max: 28},
+	"240":  {title: "ADDITIONAL ID", max: 30},
+	"241":  {title: "CUST. PART No.", max: 30},
+	"242":  {title: "MTO VARIANT", max: 6, numeric: true},
+	"243":  {title: "PCN", max: 20},
+	"250":  {title: "SECONDARY SERIAL", max: 30},
+	"251":  {title: "REF. TO SOURCE", max: 30},
+	"253":  {title: "GDTI", max: 30},
+	"254":  {title: "GLN EXTENSION COMPONENT", max: 20},
+	"255":  {title: "GCN", max: 25, numeric: true},
+	"30":   {title: "VAR. COUNT", max: 8, numeric: true},
+	"310n": {title: "NET WEIGHT (kg)", length: 6, numeric: true},
+	"311n": {title: "LENGTH (m)", length: 6, numeric: true},
+	"312n": {title: "WIDTH (m)", length: 6, numeric: true},
+	"313n": {title: "HEIGHT (m)", length: 6, numeric: true},
+	"314n": {title: "AREA (m2)", length: 6, numeric: true},
+	"315n": {title: "NET VOLUME (l)", length: 6, numeric: true},
+	"316n": {title: "NET VOLUME (m3)", length: 6, numeric: true},
+	"320n": {title: "NET WEIGHT (lb)", length: 6, numeric: true},
+	"330n": {title: "GROSS WEIGHT (kg)", length: 6, numeric: true},
+	"331n": {title: "LENGTH (m), log", length: 6, numeric: true},
+	"332n": {title: "WIDTH (m), log", length: 6, numeric: true},
+	"333n": {title: "HEIGHT (m), log", length: 6, numeric: true},
+	"334n": {title: "AREA (m2), log", length: 6, numeric: true},
+	"335n": {title: "VOLUME (l), log", length: 6, numeric: true},
+	"336n": {title: "VOLUME (m3), log", length: 6, numeric: true},
+	"37":   {title: "COUNT", max: 8, numeric: true},
+	"390n": {title: "AMOUNT", max: 15, numeric: true},
+	"391n": {title: "AMOUNT (ISO)", max: 18, numeric: true},
+	"392n": {title: "PRICE", max: 15, numeric: true},
+	"393n": {title: "PRICE (ISO)", max: 18, numeric: true},
+	"400":  {title: "ORDER NUMBER", max: 30},
+	"401":  {title: "GINC", max: 30},
+	"402":  {title: "GSIN", length: 17, numeric: true},
+	"403":  {title: "ROUTE", max: 30},
+	"410":  {title: "SHIP TO LOC", length: 13, numeric: true},
+	"411":  {title: "BILL TO", length: 13, numeric: true},
+	"412":  {title: "PURCHASE FROM", length: 13, numeric: true},
+	"413":  {title: "SHIP FOR LOC", length: 13, numeric: true},
+	"414":  {title: "LOC No.", length: 13, numeric: true},
+	"415":  {title: "PAY TO", length: 13, numeric: true},
+	"416":  {title: "PROD/SERV LOC", length: 13, numeric: true},
+	"417":  {title: "PARTY", length: 13, numeric: true},
+	"420":  {title: "SHIP TO POST", max: 20},
+	"421":  {title: "SHIP TO POST (ISO)", max: 12},
+	"422":  {title: "ORIGIN", length: 3, numeric: true},
+	"7003": {title: "EXPIRY TIME", length: 10, numeric: true},
+	"8005": {title: "PRICE PER UNIT", length: 6, numeric: true},
+	"8008": {title: "PROD TIME", max: 12, numeric: true},
+	"8020": {title: "REF No.", max: 25},
+	"90":   {title: "INTERNAL", max: 30},
+	"91":   {title: "INTERNAL", max: 90},
+	"92":   {title: "INTERNAL", max: 90},
+	"93":   {title: "INTERNAL", max: 90},
+	"94":   {title: "INTERNAL", max: 90},
+	"95":   {title: "INTERNAL", max: 90},
+	"96":   {title: "INTERNAL", max: 90},
+	"97":   {title: "INTERNAL", max: 90},
+	"98":   {title: "INTERNAL", max: 90},
+	"99":   {title: "INTERNAL", max: 90},
+}
+
+// AIM symbology identifiers that mark GS1 data
+var gs1SymbologyIDs = []string{"]C1", "]e0", "]d2", "]Q3", "]J1"}
+
+// A single element of a GS1 string
+type GS1Element struct {
+	AI    string
+	Title string
+	Value string
+}
+
+// Look up the AI at the beginning of the data
+func lookupAI(data string) (string, gs1AI, bool) {
+	for length := 2; length <= 4 && length <= len(data); length++ {
+		ai := data[:length]
+
+		if definition, ok := gs1AIs[ai]; ok {
+			return ai, definition, true
+		}
+
+		if length == 4 {
+			if definition, ok := gs1AIs[ai[:3]+"n"]; ok {
+				return ai, definition, true
+			}
+		}
+	}
+
+	return "", gs1AI{}, false
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateAIValue(ai string, definition gs1AI, value string) error {
+	if definition.length > 0 && len(value) != definition.length {
+		return fmt.Errorf("AI (%s) must be %d characters long, got %d", ai, definition.length, len(value))
+	}
+
+	if definition.max > 0 && (len(value) == 0 || len(value) > definition.max) {
+		return fmt.Errorf("AI (%s) must be 1 to %d characters long, got %d", ai, definition.max, len(value))
+	}
+
+	if definition.numeric && !isDigits(value) {
+		return fmt.Errorf("AI (%s) must be numeric", ai)
+	}
+
+	if definition.date {
+		month, _ := strconv.Atoi(value[2:4])
+		day, _ := strconv.Atoi(value[4:6])
+
+		// Day 00 means the last day of the month
+		if month < 1 || month > 12 || day > 31 {
+			return fmt.Errorf("AI (%s) is not a valid YYMMDD date", ai)
+		}
+	}
+
+	return nil
+}
+
+// Parse the human readable form of a GS1 string, e.g. (01)09501101020917(17)190508
+func parseGS1HumanReadable(data string) ([]GS1Element, error) {
+	elements := []GS1Element{}
+
+	for data != "" {
+		if data[0] != '(' {
+			return nil, fmt.Errorf("expected '(' at '%s'", data)
+		}
+
+		end := strings.Index(data, ")")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated AI at '%s'", data)
+		}
+
+		ai := data[1:end]
+		data = data[end+1:]
+
+		found, definition, ok := lookupAI(ai)
+		if !ok || found != ai {
+			return nil, fmt.Errorf("unknown AI (%s)", ai)
+		}
+
+		next := strings.Index(data, "(")
+		if next < 0 {
+			next = len(data)
+		}
+
+		value := data[:next]
+		data = data[next:]
+
+		if err := validateAIValue(ai, definition, value); err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, GS1Element{AI: ai, Title: definition.title, Value: value})
+	}
+
+	return elements, nil
+}
+
+// Parse a GS1 string (GS1-128, GS1 DataMatrix, GS1 QR, GS1 DataBar) into its
+// elements. Variable length elements are terminated by GS, which scanners
+// send in place of FNC1. A leading FNC1 or GS1 symbology identifier and a
+// trailing line terminator are ignored. The human readable form with the AIs
+// between parentheses is accepted too.
+func ParseGS1(code string) ([]GS1Element, error) {
+	data := strings.TrimRight(code, "\r\n")
+
+	for _, id := range gs1SymbologyIDs {
+		if rest, found := strings.CutPrefix(data, id); found {
+			data = rest
+			break
+		}
+	}
+
+	data = strings.TrimPrefix(data, GS)
+
+	if strings.HasPrefix(data, "(") {
+		return parseGS1HumanReadable(data)
+	}
+
+	elements := []GS1Element{}
+
+	for data != "" {
+		ai, definition, ok := lookupAI(data)
+		if !ok {
+			return nil, fmt.Errorf("unknown AI at '%s'", strings.ReplaceAll(data, GS, "<GS>"))
+		}
+
+		data = data[len(ai):]
+
+		var value string
+		if definition.length > 0 {
+			if len(data) < definition.length {
+				return nil, fmt.Errorf("AI (%s) must be %d characters long, got %d", ai, definition.length, len(data))
+			}
+
+			value = data[:definition.length]
+			data = data[definition.length:]
+		} else {
+			end := strings.Index(data, GS)
+			if end < 0 {
+				end = len(data)
+			}
+
+			value = data[:end]
+			data = data[end:]
+		}
+
+		// A separator can also follow fixed length elements
+		data = strings.TrimPrefix(data, GS)
+
+		if err := validateAIValue(ai, definition, value); err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, GS1Element{AI: ai, Title: definition.title, Value: value})
+	}
+
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("no GS1 elements found")
+	}
+
+	return elements, nil
+}
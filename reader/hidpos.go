@@ -0,0 +1,352 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"time"
+)
+
+// A scanner in USB HID POS mode, read from its hidraw device
+const SourceHIDPOS = "hidpos"
+
+// The HID usage page of barcode scanners
+const hidPOSUsagePage = 0x8C
+
+// The id of the reports carrying the scanned data on most HID POS scanners
+const HIDPOSScannedDataReport = 0x02
+
+// Longest barcode assembled from multiple reports, anything longer is
+// considered garbage
+const hidPOSMaxLength = 64 * 1024
+
+var ErrHIDPOSUnsupported = errors.New("hidpos devices are only supported on Linux")
+
+// A scanned data report of a HID POS scanner, laid out as
+//
+//	report id | data length | AIM symbology id (3) | data ... | flags
+//
+// where bit 0 of the last byte tells that the data continues in the next
+// report.
+type HIDPOSReport struct {
+	ReportID  uint8
+	AIM       string // e.g. ]E0, empty if not sent by the scanner
+	Data      []byte
+	Continued bool
+}
+
+func ParseHIDPOSReport(report []byte) (*HIDPOSReport, error) {
+	if len(report) < 6 {
+		return nil, fmt.Errorf("report too short (%d bytes)", len(report))
+	}
+
+	length := int(report[1])
+	if 5+length > len(report)-1 {
+		return nil, fmt.Errorf("data length %d exceeds the report size", length)
+	}
+
+	aim := strings.TrimRight(string(report[2:5]), "\x00")
+	if aim != "" && aim[0] != ']' {
+		// Some scanners omit the AIM flag character
+		aim = "]" + aim
+	}
+
+	return &HIDPOSReport{
+		ReportID:  report[0],
+		AIM:       aim,
+		Data:      report[5 : 5+length],
+		Continued: report[len(report)-1]&0x01 != 0,
+	}, nil
+}
+
+// The name of the symbology given by the AIM id of the report, empty if the
+// scanner has not sent it or it is unknown
+func (report *HIDPOSReport) Symbology() string {
+	symbology, _, _ := parseAIM(report.AIM)
+	return symbology
+}
+
+// Assembles barcodes split across multiple HID POS reports
+type HIDPOSDecoder struct {
+	ReportID uint8 // Reports with other ids are ignored, HIDPOSScannedDataReport if zero
+
+	aim  string
+	data []byte
+}
+
+// Push a report, returning the whole barcode (AIM id and data) once its
+// last report has been received and nil otherwise
+func (decoder *HIDPOSDecoder) Push(report []byte) (*HIDPOSReport, error) {
+	reportID := decoder.ReportID
+	if reportID == 0 {
+		reportID = HIDPOSScannedDataReport
+	}
+
+	if len(report) == 0 || report[0] != reportID {
+		return nil, nil
+	}
+
+	parsed, err := ParseHIDPOSReport(report)
+	if err != nil {
+		decoder.Reset()
+		return nil, err
+	}
+
+	if decoder.data == nil {
+		// The symbology is only meaningful in the first report
+		decoder.aim = parsed.AIM
+	}
+	decoder.data = append(decoder.data, parsed.Data...)
+
+	if len(decoder.data) > hidPOSMaxLength {
+		decoder.Reset()
+		return nil, fmt.Errorf("barcode longer than %d bytes", hidPOSMaxLength)
+	}
+
+	if parsed.Continued {
+		return nil, nil
+	}
+
+	barcode := &HIDPOSReport{
+		ReportID: reportID,
+		AIM:      decoder.aim,
+		Data:     decoder.data,
+	}
+	decoder.Reset()
+
+	return barcode, nil
+}
+
+// Drop any partially assembled barcode
+func (decoder *HIDPOSDecoder) Reset() {
+	decoder.aim = ""
+	decoder.data = nil
+}
+
+// Whether a HID report descriptor declares the given usage page
+func hasUsagePage(descriptor []byte, page uint32) bool {
+	for i := 0; i < len(descriptor); {
+		prefix := descriptor[i]
+
+		if prefix == 0xFE {
+			// Long item, its data size is in the next byte
+			if i+1 >= len(descriptor) {
+				return false
+			}
+			i += 3 + int(descriptor[i+1])
+			continue
+		}
+
+		size := int(prefix & 0x03)
+		if size == 3 {
+			size = 4
+		}
+
+		if i+1+size > len(descriptor) {
+			return false
+		}
+
+		// Usage Page is the global item with tag 0
+		if prefix&0xFC == 0x04 {
+			value := uint32(0)
+			for j := size - 1; j >= 0; j-- {
+				value = value<<8 | uint32(descriptor[i+1+j])
+			}
+
+			if value == page {
+				return true
+			}
+		}
+
+		i += 1 + size
+	}
+
+	return false
+}
+
+// Find the single HID POS device matching a selector
+func findHIDPOSDevice(selector *DeviceSelector) (*DeviceInfo, error) {
+	matches, err := ListHIDPOSDevices(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrDeviceNotFound
+	}
+
+	if len(matches) > 1 {
+		paths := make([]string, len(matches))
+		for i, match := range matches {
+			paths[i] = match.Path
+		}
+
+		return nil, fmt.Errorf("%w (%s)", ErrAmbiguousDevice, strings.Join(paths, ", "))
+	}
+
+	return matches[0], nil
+}
+
+// Reads whole barcodes from a scanner in USB HID POS mode, without any
+// keystroke decoding
+type HIDPOSReader struct {
+	DeviceID string
+	Selector DeviceSelector
+	ReportID uint8 // HIDPOSScannedDataReport if zero
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+func (hidposReader *HIDPOSReader) readCharacters(ctx context.Context, characters chan input) {
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	for {
+		info, err := findHIDPOSDevice(&hidposReader.Selector)
+
+		var device io.ReadCloser
+		if err == nil {
+			device, err = openHIDPOS(info.Path)
+		}
+
+		if err != nil {
+			if !errors.Is(err, ErrDeviceNotFound) && err.Error() != lastError {
+				hidposReader.logger.Error("Cannot open device: %s", err)
+			}
+			lastError = err.Error()
+		} else {
+			lastError = ""
+
+			hidposReader.logger.Info("Device %s connected", info.Path)
+			hidposReader.setStatus(hidposReader.DeviceID, StatusConnected)
+
+			err = hidposReader.readDevice(ctx, device, characters)
+			if ctx.Err() != nil {
+				return
+			}
+
+			hidposReader.logger.Error("Lost device %s: %s", info.Path, err)
+		}
+
+		hidposReader.setStatus(hidposReader.DeviceID, StatusDisconnected)
+
+		// hidraw nodes are not covered by the hotplug events of input devices
+		select {
+		case <-time.After(pollingInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Read barcodes from an open device until it fails or the context is done,
+// the device is closed before returning
+func (hidposReader *HIDPOSReader) readDevice(ctx context.Context, device io.ReadCloser, characters chan input) error {
+	// Closing the device unblocks the pending read
+	stop := context.AfterFunc(ctx, func() { device.Close() })
+	defer func() {
+		if stop() {
+			device.Close()
+		}
+	}()
+
+	decoder := HIDPOSDecoder{ReportID: hidposReader.ReportID}
+
+	// Each read returns a single report
+	report := make([]byte, 4096)
+
+	for {
+		n, err := device.Read(report)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+
+		barcode, err := decoder.Push(report[:n])
+		if err != nil {
+			hidposReader.logger.Error("Invalid report: %s", err)
+			continue
+		}
+
+		if barcode == nil {
+			continue
+		}
+
+		decoded, _ := decodeBytes(barcode.Data, nil)
+		content := strings.Join(decoded, "")
+
+		// The AIM id is left for the assembler to parse and strip
+		if hidposReader.Symbology == SymbologyAIM {
+			content = barcode.AIM + content
+		}
+
+		select {
+		case characters <- input{text: content, symbology: barcode.Symbology()}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (hidposReader *HIDPOSReader) Run(ctx context.Context, scans chan<- Scan) {
+	if hidposReader.logger == nil {
+		hidposReader.logger = logging.GetLogger("READER:" + hidposReader.DeviceID)
+	}
+
+	// Each report carries a whole barcode
+	options := hidposReader.ScanOptions
+	options.Framing = FramingMessage
+
+	scanAssembler := newAssembler(options, hidposReader.DeviceID, hidposReader.logger)
+	scanAssembler.run(ctx, hidposReader.readCharacters, scans)
+
+	hidposReader.setStatus(hidposReader.DeviceID, StatusStopped)
+}
+
+func (hidposReader *HIDPOSReader) ID() string {
+	return hidposReader.DeviceID
+}
+
+func newHIDPOSSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	selector, err := NewDeviceSelector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HIDPOSReader{
+		DeviceID:    config.ID,
+		Selector:    selector,
+		ReportID:    config.ReportID,
+		ScanOptions: options,
+	}, nil
+}
+
+func init() {
+	RegisterSource(SourceHIDPOS, newHIDPOSSource)
+}
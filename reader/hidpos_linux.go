@@ -0,0 +1,113 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Identification data of a hidraw device, nil if it is not a barcode scanner
+// in HID POS mode
+func getHIDPOSInfo(path string) (*DeviceInfo, error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	size, err := unix.IoctlGetInt(fd, unix.HIDIOCGRDESCSIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor := unix.HIDRawReportDescriptor{Size: uint32(size)}
+	err = unix.IoctlHIDGetDesc(fd, &descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasUsagePage(descriptor.Value[:descriptor.Size], hidPOSUsagePage) {
+		return nil, nil
+	}
+
+	ids, err := unix.IoctlHIDGetRawInfo(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name, physical location and unique id are optional
+	name, _ := unix.IoctlHIDGetRawName(fd)
+	phys, _ := unix.IoctlHIDGetRawPhys(fd)
+	uniq, _ := unix.IoctlHIDGetRawUniq(fd)
+
+	vid := uint16(ids.Vendor)
+	pid := uint16(ids.Product)
+
+	return &DeviceInfo{
+		Path: path,
+		Name: name,
+		Phys: phys,
+		Uniq: uniq,
+		VID:  vid,
+		PID:  pid,
+		HWID: fmt.Sprintf(
+			"VID_%04X&PID_%04X&BUS_%04X&NAME_%s&PHYS_%s&UNIQ_%s",
+			vid, pid, ids.Bustype, name, phys, uniq,
+		),
+	}, nil
+}
+
+// List the barcode scanners in HID POS mode matching a selector
+func ListHIDPOSDevices(selector *DeviceSelector) ([]*DeviceInfo, error) {
+	paths, err := filepath.Glob("/dev/hidraw*")
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []*DeviceInfo{}
+
+	for _, path := range paths {
+		info, err := getHIDPOSInfo(path)
+		if err != nil || info == nil {
+			continue
+		}
+
+		if selector.Match(info) {
+			matches = append(matches, info)
+		}
+	}
+
+	return matches, nil
+}
+
+// Open a hidraw device for reading its reports
+func openHIDPOS(path string) (io.ReadCloser, error) {
+	// Opened non blocking so that reads go through the runtime poller and
+	// can be interrupted by closing the file
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	return os.NewFile(uintptr(fd), path), nil
+}
@@ -0,0 +1,222 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const inputDir = "/dev/input"
+
+// An input device node being added to or removed from /dev/input
+type HotplugEvent struct {
+	Path    string
+	Removed bool
+}
+
+// Watches /dev/input with inotify, shared by all the readers. It is started
+// by the first subscriber and runs for the whole life of the process.
+type hotplugWatcher struct {
+	mutex       sync.Mutex
+	started     bool
+	err         error
+	subscribers map[chan HotplugEvent]bool
+	logger      *logging.Logger
+}
+
+var hotplug = hotplugWatcher{
+	subscribers: map[chan HotplugEvent]bool{},
+	logger:      logging.GetLogger("HOTPLUG"),
+}
+
+func (watcher *hotplugWatcher) start() error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	// Device nodes are created before udev sets their permissions, so
+	// attribute changes are watched too
+	_, err = unix.InotifyAddWatch(fd, inputDir, unix.IN_CREATE|unix.IN_DELETE|unix.IN_ATTRIB)
+	if err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go watcher.watch(fd)
+
+	return nil
+}
+
+func (watcher *hotplugWatcher) watch(fd int) {
+	buffer := make([]byte, 4096)
+
+	for {
+		n, err := unix.Read(fd, buffer)
+		if err == unix.EINTR {
+			continue
+		}
+
+		if err != nil {
+			watcher.logger.Error("Stopped watching %s: %s", inputDir, err)
+			return
+		}
+
+		// Each event is a struct inotify_event followed by the file name
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			mask := binary.NativeEndian.Uint32(buffer[offset+4:])
+			length := int(binary.NativeEndian.Uint32(buffer[offset+12:]))
+
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buffer[nameStart:nameStart+length]), "\x00")
+			offset = nameStart + length
+
+			if mask&unix.IN_Q_OVERFLOW != 0 {
+				watcher.logger.Error("Hotplug events lost, rescanning %s", inputDir)
+				watcher.rescan()
+				continue
+			}
+
+			if !strings.HasPrefix(name, "event") {
+				continue
+			}
+
+			watcher.publish(HotplugEvent{
+				Path:    filepath.Join(inputDir, name),
+				Removed: mask&unix.IN_DELETE != 0,
+			})
+		}
+	}
+}
+
+// Publish every device node currently in /dev/input, after events have been
+// lost because the inotify queue overflowed
+func (watcher *hotplugWatcher) rescan() {
+	entries, err := os.ReadDir(inputDir)
+	if err != nil {
+		watcher.logger.Error("Cannot rescan %s: %s", inputDir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "event") {
+			watcher.publish(HotplugEvent{Path: filepath.Join(inputDir, entry.Name())})
+		}
+	}
+}
+
+func (watcher *hotplugWatcher) publish(event HotplugEvent) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	for subscriber := range watcher.subscribers {
+		// Never block the watcher, subscribers only need to know that
+		// something has changed
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe to the hotplug events of input devices, returning the events
+// channel and the function to unsubscribe
+func SubscribeHotplug() (chan HotplugEvent, func(), error) {
+	hotplug.mutex.Lock()
+	defer hotplug.mutex.Unlock()
+
+	if !hotplug.started {
+		hotplug.err = hotplug.start()
+		hotplug.started = true
+	}
+
+	if hotplug.err != nil {
+		return nil, nil, hotplug.err
+	}
+
+	events := make(chan HotplugEvent, 16)
+	hotplug.subscribers[events] = true
+
+	unsubscribe := func() {
+		hotplug.mutex.Lock()
+		defer hotplug.mutex.Unlock()
+
+		delete(hotplug.subscribers, events)
+	}
+
+	return events, unsubscribe, nil
+}
+
+// Return a channel signaled whenever input devices are added or removed,
+// until the context is done. Falls back to polling if hotplug events are
+// not available.
+func watchDevices(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	notify := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	events, unsubscribe, err := SubscribeHotplug()
+	if err != nil {
+		hotplug.logger.Error("Hotplug events not available, polling devices: %s", err)
+
+		go func() {
+			ticker := time.NewTicker(pollingInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					notify()
+				}
+			}
+		}()
+
+		return changes
+	}
+
+	go func() {
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-events:
+				notify()
+			}
+		}
+	}()
+
+	return changes
+}
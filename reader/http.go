@@ -0,0 +1,338 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Scans posted over HTTP, e.g. by mobile scanning apps
+const SourceHTTP = "http"
+
+// Largest request body accepted
+const maxHTTPBody = 64 * 1024
+
+// The body of a JSON scan request
+type httpScanRequest struct {
+	Content string `json:"content"`
+}
+
+// A token bucket, refilled at a fixed rate
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64 // Tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Take a token if available, otherwise return how long to wait for one
+func (limiter *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if limiter.last.IsZero() {
+		limiter.tokens = limiter.burst
+	} else {
+		limiter.tokens = math.Min(limiter.burst, limiter.tokens+now.Sub(limiter.last).Seconds()*limiter.rate)
+	}
+	limiter.last = now
+
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - limiter.tokens) / limiter.rate * float64(time.Second))
+}
+
+// Serves POST /scans, accepting JSON ({"content": "..."}) and form encoded
+// (content=...) scans. Each client authenticates with its own API token,
+// which selects the device id of its scans.
+type HTTPReader struct {
+	DeviceID string
+	Address  string // e.g. :8080
+	// Device ids by API token, sent as "Authorization: Bearer <token>"
+	Tokens map[string]string
+	// Requests per second allowed for each token, zero disables the limit
+	RateLimit float64
+	// Requests allowed in a burst above the rate limit, at least 1
+	Burst int
+	ScanOptions
+
+	limiters map[string]*rateLimiter
+	logger   *logging.Logger
+	sourceState
+}
+
+func (httpReader *HTTPReader) ID() string {
+	return httpReader.DeviceID
+}
+
+func writeJSON(w http.ResponseWriter, status int, body map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// Find the device id of the token sent with a request
+func (httpReader *HTTPReader) authenticate(r *http.Request) (string, string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", "", false
+	}
+
+	deviceID, ok := httpReader.Tokens[strings.TrimSpace(token)]
+
+	return strings.TrimSpace(token), deviceID, ok
+}
+
+// Read the scan content from a JSON or form request body
+func readScanContent(r *http.Request) (string, int, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", http.StatusUnsupportedMediaType, errors.New("missing or invalid content type")
+	}
+
+	var content string
+
+	switch mediaType {
+	case "application/json":
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		var request httpScanRequest
+		if err := decoder.Decode(&request); err != nil {
+			return "", http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err)
+		}
+
+		content = request.Content
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := r.ParseMultipartForm(maxHTTPBody); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return "", http.StatusBadRequest, fmt.Errorf("invalid form body: %w", err)
+		}
+
+		content = r.PostFormValue("content")
+	default:
+		return "", http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type '%s'", mediaType)
+	}
+
+	if content == "" {
+		return "", http.StatusBadRequest, errors.New("missing content")
+	}
+
+	if !utf8.ValidString(content) {
+		return "", http.StatusBadRequest, errors.New("content is not valid UTF-8")
+	}
+
+	return content, 0, nil
+}
+
+func (httpReader *HTTPReader) handleScan(ctx context.Context, scans chan<- Scan) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, deviceID, ok := httpReader.authenticate(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+
+		if limiter, ok := httpReader.limiters[token]; ok {
+			allowed, wait := limiter.allow(time.Now())
+			if !allowed {
+				w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxHTTPBody)
+
+		content, status, err := readScanContent(r)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			writeError(w, status, err.Error())
+			return
+		}
+
+		if httpReader.MaxLength > 0 && utf8.RuneCountInString(content) > httpReader.MaxLength {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("content longer than %d characters", httpReader.MaxLength))
+			return
+		}
+
+		// Each request carries a whole scan
+		options := httpReader.ScanOptions
+		options.Framing = FramingMessage
+
+		scanAssembler := newAssembler(options, deviceID, httpReader.logger)
+		scanAssembler.buffer = content
+
+		accepted := make(chan Scan, 1)
+		if !scanAssembler.emit(accepted) {
+			writeError(w, http.StatusUnprocessableEntity, "invalid scan")
+			return
+		}
+
+		// The scans channel can be full, the request must not outlive the
+		// reader waiting for room
+		select {
+		case scans <- <-accepted:
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, "scan not delivered")
+			return
+		case <-ctx.Done():
+			writeError(w, http.StatusServiceUnavailable, "the reader is stopping")
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]string{"device": deviceID})
+	}
+}
+
+func (httpReader *HTTPReader) serve(ctx context.Context, scans chan<- Scan) error {
+	var listenConfig net.ListenConfig
+
+	listener, err := listenConfig.Listen(ctx, "tcp", httpReader.Address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", httpReader.handleScan(ctx, scans))
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+	}
+
+	// Let the pending requests complete before stopping, so that no scan is
+	// sent once the reader has returned
+	shutdown := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(shutdown)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	})
+	defer func() {
+		if !stop() {
+			<-shutdown
+		}
+	}()
+
+	httpReader.logger.Info("Listening on http %s", listener.Addr())
+	httpReader.setStatus(httpReader.DeviceID, StatusConnected)
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func (httpReader *HTTPReader) Run(ctx context.Context, scans chan<- Scan) {
+	if httpReader.logger == nil {
+		httpReader.logger = logging.GetLogger("READER:" + httpReader.DeviceID)
+	}
+
+	httpReader.limiters = map[string]*rateLimiter{}
+	if httpReader.RateLimit > 0 {
+		for token := range httpReader.Tokens {
+			httpReader.limiters[token] = &rateLimiter{
+				rate:  httpReader.RateLimit,
+				burst: math.Max(1, float64(httpReader.Burst)),
+			}
+		}
+	}
+
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	for {
+		err := httpReader.serve(ctx, scans)
+
+		if ctx.Err() != nil {
+			httpReader.logger.Info("Stopping http reader: %s", httpReader.DeviceID)
+			httpReader.setStatus(httpReader.DeviceID, StatusStopped)
+			return
+		}
+
+		if err.Error() != lastError {
+			httpReader.logger.Error("Cannot serve on %s: %s", httpReader.Address, err)
+		}
+		lastError = err.Error()
+
+		httpReader.setStatus(httpReader.DeviceID, StatusDisconnected)
+
+		select {
+		case <-time.After(pollingInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func newHTTPSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Address == "" {
+		return nil, errors.New("the http type requires an address")
+	}
+
+	if len(config.Tokens) == 0 {
+		return nil, errors.New("the http type requires at least one token")
+	}
+
+	if config.RateLimit < 0 || config.Burst < 0 {
+		return nil, errors.New("rate_limit and burst cannot be negative")
+	}
+
+	return &HTTPReader{
+		DeviceID:    config.ID,
+		Address:     config.Address,
+		Tokens:      config.Tokens,
+		RateLimit:   config.RateLimit,
+		Burst:       config.Burst,
+		ScanOptions: options,
+	}, nil
+}
+
+func init() {
+	RegisterSource(SourceHTTP, newHTTPSource)
+}
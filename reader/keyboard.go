@@ -0,0 +1,244 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import "strings"
+
+// Keeps track of the modifiers state of a keyboard and decodes key presses
+// into characters using a layout.
+type Keyboard struct {
+	Layout *Layout
+
+	// How Alt+numpad codes are decoded, defaults to AltCodesCP437
+	AltCodes string
+
+	leftShift  bool
+	rightShift bool
+	capsLock   bool
+	leftCtrl   bool
+	rightCtrl  bool
+	leftAlt    bool
+	rightAlt   bool
+
+	// The dead key waiting to be composed with the next character, if any
+	pendingDead string
+
+	// The Alt+numpad code typed so far, decoded when alt is released
+	altCode string
+}
+
+// Release all modifiers, turn caps lock off and drop any pending dead key,
+// to be called when the device is (re)connected.
+func (keyboard *Keyboard) Reset() {
+	*keyboard = Keyboard{Layout: keyboard.Layout, AltCodes: keyboard.AltCodes}
+}
+
+func (keyboard *Keyboard) layout() *Layout {
+	if keyboard.Layout == nil {
+		return LayoutUS
+	}
+
+	return keyboard.Layout
+}
+
+// Whether an alt key which is not acting as AltGr is held down
+func (keyboard *Keyboard) alt() bool {
+	return keyboard.leftAlt || (keyboard.rightAlt && !keyboard.layout().AltGr)
+}
+
+func (keyboard *Keyboard) shift() bool {
+	return keyboard.leftShift || keyboard.rightShift
+}
+
+func (keyboard *Keyboard) ctrl() bool {
+	return keyboard.leftCtrl || keyboard.rightCtrl
+}
+
+// The Linux input event type of key events (EV_KEY)
+const EventKey = 1
+
+// Handle a raw Linux input event, as read from evdev or from a recording,
+// and return the characters produced, if any.
+func (keyboard *Keyboard) HandleEvent(eventType uint16, code uint16, value int32) string {
+	if eventType != EventKey {
+		// Don't care about other events
+		return ""
+	}
+
+	if value == 2 {
+		// Autorepeat event, scanners never hold keys down
+		return ""
+	}
+
+	// Key releases are needed too, to keep track of the modifiers state
+	return keyboard.HandleKey(code, value == 1)
+}
+
+// Handle a key event (pressed is false for key releases) and return the
+// characters produced, if any.
+func (keyboard *Keyboard) HandleKey(code uint16, pressed bool) string {
+	switch code {
+	case KeyLeftShift:
+		keyboard.leftShift = pressed
+		return ""
+	case KeyRightShift:
+		keyboard.rightShift = pressed
+		return ""
+	case KeyLeftCtrl:
+		keyboard.leftCtrl = pressed
+		return ""
+	case KeyRightCtrl:
+		keyboard.rightCtrl = pressed
+		return ""
+	case KeyLeftAlt:
+		keyboard.leftAlt = pressed
+		return keyboard.finishAltCode()
+	case KeyRightAlt:
+		keyboard.rightAlt = pressed
+		return keyboard.finishAltCode()
+	case KeyCapsLock:
+		if pressed {
+			keyboard.capsLock = !keyboard.capsLock
+		}
+		return ""
+	}
+
+	if !pressed {
+		return ""
+	}
+
+	layout := keyboard.layout()
+
+	chars, ok := layout.Keys[code]
+	if !ok {
+		return ""
+	}
+
+	// Alt combinations do not produce characters, apart from the numpad
+	// digits which are collected as an Alt+numpad code
+	if keyboard.alt() {
+		keyboard.collectAltCode(code, chars)
+		return ""
+	}
+
+	// Right alt is AltGr on layouts that have one, on Windows it is also
+	// reported together with a left ctrl press which must be ignored
+	altGr := keyboard.rightAlt && layout.AltGr
+
+	shifted := keyboard.shift()
+	if chars.Letter && keyboard.capsLock {
+		shifted = !shifted
+	}
+
+	var character string
+	switch {
+	case altGr && shifted:
+		character = chars.ShiftedAltGr
+	case altGr:
+		character = chars.AltGr
+	case shifted:
+		character = chars.Shifted
+	default:
+		character = chars.Normal
+	}
+
+	if keyboard.ctrl() && !altGr {
+		return controlCharacter(character)
+	}
+
+	return keyboard.applyDeadKey(character)
+}
+
+// Collect a key pressed while holding alt as part of an Alt+numpad code.
+// Numpad plus starts a hexadecimal code, whose digits can also be typed
+// with the letter keys.
+func (keyboard *Keyboard) collectAltCode(code uint16, chars KeyChars) {
+	if digit, ok := numpadDigits[code]; ok {
+		keyboard.altCode += digit
+		return
+	}
+
+	if code == KeyKPPlus && keyboard.altCode == "" {
+		keyboard.altCode = "+"
+		return
+	}
+
+	isHexLetter := len(chars.Normal) == 1 && strings.Contains("abcdef", chars.Normal)
+	if strings.HasPrefix(keyboard.altCode, "+") && isHexLetter {
+		keyboard.altCode += chars.Normal
+		return
+	}
+
+	// Not an Alt+numpad code after all
+	keyboard.altCode = ""
+}
+
+// Decode the Alt+numpad code typed so far once alt has been released
+func (keyboard *Keyboard) finishAltCode() string {
+	if keyboard.alt() || keyboard.altCode == "" {
+		return ""
+	}
+
+	digits := keyboard.altCode
+	keyboard.altCode = ""
+
+	return decodeAltCode(digits, keyboard.AltCodes)
+}
+
+// Keep track of dead keys, composing them with the following character
+func (keyboard *Keyboard) applyDeadKey(character string) string {
+	if character == "" {
+		return ""
+	}
+
+	pending := keyboard.pendingDead
+
+	if isDeadKey(character) {
+		keyboard.pendingDead = character
+		if pending != "" {
+			// Two dead keys in a row, the first one is typed as is
+			return composeDeadKey(pending, " ")
+		}
+
+		return ""
+	}
+
+	if pending == "" {
+		return character
+	}
+
+	keyboard.pendingDead = ""
+
+	return composeDeadKey(pending, character)
+}
+
+// Convert a character typed while holding ctrl to the matching ASCII control
+// character (e.g. ctrl+] is GS, used by scanners as the GS1 separator).
+func controlCharacter(character string) string {
+	if len(character) != 1 {
+		return ""
+	}
+
+	c := strings.ToUpper(character)[0]
+	if c <= '@' || c > '_' {
+		// Also skips ctrl+@ (NUL)
+		return ""
+	}
+
+	return string(rune(c - '@'))
+}
@@ -0,0 +1,377 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Characters produced by dead keys, as combining marks
+const (
+	DeadGrave      = "\u0300"
+	DeadAcute      = "\u0301"
+	DeadCircumflex = "\u0302"
+	DeadTilde      = "\u0303"
+	DeadDiaeresis  = "\u0308"
+)
+
+type deadKey struct {
+	// The character typed when the dead key is followed by space or by a
+	// character it cannot be composed with
+	spacing string
+	// The characters that can be composed with this dead key and the results
+	bases    []rune
+	composed []rune
+}
+
+var deadKeys = map[string]deadKey{
+	DeadGrave:      {"`", []rune("aeiouAEIOU"), []rune("àèìòùÀÈÌÒÙ")},
+	DeadAcute:      {"´", []rune("aeiouyAEIOUY"), []rune("áéíóúýÁÉÍÓÚÝ")},
+	DeadCircumflex: {"^", []rune("aeiouAEIOU"), []rune("âêîôûÂÊÎÔÛ")},
+	DeadTilde:      {"~", []rune("anoANO"), []rune("ãñõÃÑÕ")},
+	DeadDiaeresis:  {"¨", []rune("aeiouyAEIOUY"), []rune("äëïöüÿÄËÏÖÜŸ")},
+}
+
+func isDeadKey(character string) bool {
+	_, ok := deadKeys[character]
+	return ok
+}
+
+// Compose a dead key with the character typed after it
+func composeDeadKey(dead string, character string) string {
+	key := deadKeys[dead]
+
+	if character == " " {
+		return key.spacing
+	}
+
+	runes := []rune(character)
+	if len(runes) == 1 {
+		for i, base := range key.bases {
+			if base == runes[0] {
+				return string(key.composed[i])
+			}
+		}
+	}
+
+	return key.spacing + character
+}
+
+var layouts = map[string]*Layout{}
+
+// Make a layout available to devices through the "layout" configuration option
+func RegisterLayout(layout *Layout) {
+	layouts[layout.Name] = layout
+}
+
+// Get a registered layout by name, an empty name selects the US layout
+func GetLayout(name string) (*Layout, error) {
+	if name == "" {
+		return LayoutUS, nil
+	}
+
+	layout, ok := layouts[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout '%s' (available: %s)", name, strings.Join(LayoutNames(), ", "))
+	}
+
+	return layout, nil
+}
+
+// Names of all the registered layouts, sorted
+func LayoutNames() []string {
+	names := make([]string, 0, len(layouts))
+	for name := range layouts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	RegisterLayout(LayoutUS)
+	RegisterLayout(LayoutUK)
+	RegisterLayout(LayoutDE)
+	RegisterLayout(LayoutFR)
+	RegisterLayout(LayoutIT)
+	RegisterLayout(LayoutES)
+}
+
+// UK 105-key layout
+var LayoutUK = newLayout("uk", map[uint16]KeyChars{
+	KeyGrave:      {Normal: "`", Shifted: "¬", AltGr: "¦"},
+	2:             {Normal: "1", Shifted: "!"},
+	3:             {Normal: "2", Shifted: "\""},
+	4:             {Normal: "3", Shifted: "£"},
+	5:             {Normal: "4", Shifted: "$", AltGr: "€"},
+	6:             {Normal: "5", Shifted: "%"},
+	7:             {Normal: "6", Shifted: "^"},
+	8:             {Normal: "7", Shifted: "&"},
+	9:             {Normal: "8", Shifted: "*"},
+	10:            {Normal: "9", Shifted: "("},
+	11:            {Normal: "0", Shifted: ")"},
+	KeyMinus:      {Normal: "-", Shifted: "_"},
+	KeyEqual:      {Normal: "=", Shifted: "+"},
+	16:            letter("q", "Q"),
+	17:            letter("w", "W"),
+	18:            {Normal: "e", Shifted: "E", AltGr: "é", ShiftedAltGr: "É", Letter: true},
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("y", "Y"),
+	22:            {Normal: "u", Shifted: "U", AltGr: "ú", ShiftedAltGr: "Ú", Letter: true},
+	23:            {Normal: "i", Shifted: "I", AltGr: "í", ShiftedAltGr: "Í", Letter: true},
+	24:            {Normal: "o", Shifted: "O", AltGr: "ó", ShiftedAltGr: "Ó", Letter: true},
+	25:            letter("p", "P"),
+	KeyLeftBrace:  {Normal: "[", Shifted: "{"},
+	KeyRightBrace: {Normal: "]", Shifted: "}"},
+	30:            {Normal: "a", Shifted: "A", AltGr: "á", ShiftedAltGr: "Á", Letter: true},
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  {Normal: ";", Shifted: ":"},
+	KeyApostrophe: {Normal: "'", Shifted: "@"},
+	KeyBackslash:  {Normal: "#", Shifted: "~"},
+	Key102nd:      {Normal: "\\", Shifted: "|"},
+	44:            letter("z", "Z"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            letter("m", "M"),
+	KeyComma:      {Normal: ",", Shifted: "<"},
+	KeyDot:        {Normal: ".", Shifted: ">"},
+	KeySlash:      {Normal: "/", Shifted: "?"},
+})
+
+// German QWERTZ layout
+var LayoutDE = newLayout("de", map[uint16]KeyChars{
+	KeyGrave:      {Normal: DeadCircumflex, Shifted: "°"},
+	2:             {Normal: "1", Shifted: "!"},
+	3:             {Normal: "2", Shifted: "\"", AltGr: "²"},
+	4:             {Normal: "3", Shifted: "§", AltGr: "³"},
+	5:             {Normal: "4", Shifted: "$"},
+	6:             {Normal: "5", Shifted: "%"},
+	7:             {Normal: "6", Shifted: "&"},
+	8:             {Normal: "7", Shifted: "/", AltGr: "{"},
+	9:             {Normal: "8", Shifted: "(", AltGr: "["},
+	10:            {Normal: "9", Shifted: ")", AltGr: "]"},
+	11:            {Normal: "0", Shifted: "=", AltGr: "}"},
+	KeyMinus:      {Normal: "ß", Shifted: "?", AltGr: "\\"},
+	KeyEqual:      {Normal: DeadAcute, Shifted: DeadGrave},
+	16:            {Normal: "q", Shifted: "Q", AltGr: "@", Letter: true},
+	17:            letter("w", "W"),
+	18:            {Normal: "e", Shifted: "E", AltGr: "€", Letter: true},
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("z", "Z"),
+	22:            letter("u", "U"),
+	23:            letter("i", "I"),
+	24:            letter("o", "O"),
+	25:            letter("p", "P"),
+	KeyLeftBrace:  letter("ü", "Ü"),
+	KeyRightBrace: {Normal: "+", Shifted: "*", AltGr: "~"},
+	30:            letter("a", "A"),
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  letter("ö", "Ö"),
+	KeyApostrophe: letter("ä", "Ä"),
+	KeyBackslash:  {Normal: "#", Shifted: "'"},
+	Key102nd:      {Normal: "<", Shifted: ">", AltGr: "|"},
+	44:            letter("y", "Y"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            {Normal: "m", Shifted: "M", AltGr: "µ", Letter: true},
+	KeyComma:      {Normal: ",", Shifted: ";"},
+	KeyDot:        {Normal: ".", Shifted: ":"},
+	KeySlash:      {Normal: "-", Shifted: "_"},
+})
+
+// French AZERTY layout
+var LayoutFR = newLayout("fr", map[uint16]KeyChars{
+	KeyGrave:      {Normal: "²", Shifted: ""},
+	2:             {Normal: "&", Shifted: "1"},
+	3:             {Normal: "é", Shifted: "2", AltGr: DeadTilde},
+	4:             {Normal: "\"", Shifted: "3", AltGr: "#"},
+	5:             {Normal: "'", Shifted: "4", AltGr: "{"},
+	6:             {Normal: "(", Shifted: "5", AltGr: "["},
+	7:             {Normal: "-", Shifted: "6", AltGr: "|"},
+	8:             {Normal: "è", Shifted: "7", AltGr: DeadGrave},
+	9:             {Normal: "_", Shifted: "8", AltGr: "\\"},
+	10:            {Normal: "ç", Shifted: "9", AltGr: "^"},
+	11:            {Normal: "à", Shifted: "0", AltGr: "@"},
+	KeyMinus:      {Normal: ")", Shifted: "°", AltGr: "]"},
+	KeyEqual:      {Normal: "=", Shifted: "+", AltGr: "}"},
+	16:            letter("a", "A"),
+	17:            letter("z", "Z"),
+	18:            {Normal: "e", Shifted: "E", AltGr: "€", Letter: true},
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("y", "Y"),
+	22:            letter("u", "U"),
+	23:            letter("i", "I"),
+	24:            letter("o", "O"),
+	25:            letter("p", "P"),
+	KeyLeftBrace:  {Normal: DeadCircumflex, Shifted: DeadDiaeresis},
+	KeyRightBrace: {Normal: "$", Shifted: "£", AltGr: "¤"},
+	30:            letter("q", "Q"),
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  letter("m", "M"),
+	KeyApostrophe: {Normal: "ù", Shifted: "%"},
+	KeyBackslash:  {Normal: "*", Shifted: "µ"},
+	Key102nd:      {Normal: "<", Shifted: ">"},
+	44:            letter("w", "W"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            {Normal: ",", Shifted: "?"},
+	KeyComma:      {Normal: ";", Shifted: "."},
+	KeyDot:        {Normal: ":", Shifted: "/"},
+	KeySlash:      {Normal: "!", Shifted: "§"},
+})
+
+// Italian layout
+var LayoutIT = newLayout("it", map[uint16]KeyChars{
+	KeyGrave:      {Normal: "\\", Shifted: "|"},
+	2:             {Normal: "1", Shifted: "!"},
+	3:             {Normal: "2", Shifted: "\""},
+	4:             {Normal: "3", Shifted: "£"},
+	5:             {Normal: "4", Shifted: "$"},
+	6:             {Normal: "5", Shifted: "%", AltGr: "€"},
+	7:             {Normal: "6", Shifted: "&"},
+	8:             {Normal: "7", Shifted: "/"},
+	9:             {Normal: "8", Shifted: "("},
+	10:            {Normal: "9", Shifted: ")"},
+	11:            {Normal: "0", Shifted: "="},
+	KeyMinus:      {Normal: "'", Shifted: "?"},
+	KeyEqual:      {Normal: "ì", Shifted: "^"},
+	16:            letter("q", "Q"),
+	17:            letter("w", "W"),
+	18:            {Normal: "e", Shifted: "E", AltGr: "€", Letter: true},
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("y", "Y"),
+	22:            letter("u", "U"),
+	23:            letter("i", "I"),
+	24:            letter("o", "O"),
+	25:            letter("p", "P"),
+	KeyLeftBrace:  {Normal: "è", Shifted: "é", AltGr: "[", ShiftedAltGr: "{"},
+	KeyRightBrace: {Normal: "+", Shifted: "*", AltGr: "]", ShiftedAltGr: "}"},
+	30:            letter("a", "A"),
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  {Normal: "ò", Shifted: "ç", AltGr: "@"},
+	KeyApostrophe: {Normal: "à", Shifted: "°", AltGr: "#"},
+	KeyBackslash:  {Normal: "ù", Shifted: "§"},
+	Key102nd:      {Normal: "<", Shifted: ">"},
+	44:            letter("z", "Z"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            letter("m", "M"),
+	KeyComma:      {Normal: ",", Shifted: ";"},
+	KeyDot:        {Normal: ".", Shifted: ":"},
+	KeySlash:      {Normal: "-", Shifted: "_"},
+})
+
+// Spanish layout
+var LayoutES = newLayout("es", map[uint16]KeyChars{
+	KeyGrave:      {Normal: "º", Shifted: "ª", AltGr: "\\"},
+	2:             {Normal: "1", Shifted: "!", AltGr: "|"},
+	3:             {Normal: "2", Shifted: "\"", AltGr: "@"},
+	4:             {Normal: "3", Shifted: "·", AltGr: "#"},
+	5:             {Normal: "4", Shifted: "$", AltGr: DeadTilde},
+	6:             {Normal: "5", Shifted: "%", AltGr: "€"},
+	7:             {Normal: "6", Shifted: "&", AltGr: "¬"},
+	8:             {Normal: "7", Shifted: "/"},
+	9:             {Normal: "8", Shifted: "("},
+	10:            {Normal: "9", Shifted: ")"},
+	11:            {Normal: "0", Shifted: "="},
+	KeyMinus:      {Normal: "'", Shifted: "?"},
+	KeyEqual:      {Normal: "¡", Shifted: "¿"},
+	16:            letter("q", "Q"),
+	17:            letter("w", "W"),
+	18:            {Normal: "e", Shifted: "E", AltGr: "€", Letter: true},
+	19:            letter("r", "R"),
+	20:            letter("t", "T"),
+	21:            letter("y", "Y"),
+	22:            letter("u", "U"),
+	23:            letter("i", "I"),
+	24:            letter("o", "O"),
+	25:            letter("p", "P"),
+	KeyLeftBrace:  {Normal: DeadGrave, Shifted: DeadCircumflex, AltGr: "["},
+	KeyRightBrace: {Normal: "+", Shifted: "*", AltGr: "]"},
+	30:            letter("a", "A"),
+	31:            letter("s", "S"),
+	32:            letter("d", "D"),
+	33:            letter("f", "F"),
+	34:            letter("g", "G"),
+	35:            letter("h", "H"),
+	36:            letter("j", "J"),
+	37:            letter("k", "K"),
+	38:            letter("l", "L"),
+	KeySemicolon:  letter("ñ", "Ñ"),
+	KeyApostrophe: {Normal: DeadAcute, Shifted: DeadDiaeresis, AltGr: "{"},
+	KeyBackslash:  {Normal: "ç", Shifted: "Ç", AltGr: "}", Letter: true},
+	Key102nd:      {Normal: "<", Shifted: ">"},
+	44:            letter("z", "Z"),
+	45:            letter("x", "X"),
+	46:            letter("c", "C"),
+	47:            letter("v", "V"),
+	48:            letter("b", "B"),
+	49:            letter("n", "N"),
+	50:            letter("m", "M"),
+	KeyComma:      {Normal: ",", Shifted: ";"},
+	KeyDot:        {Normal: ".", Shifted: ":"},
+	KeySlash:      {Normal: "-", Shifted: "_"},
+})
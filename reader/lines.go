@@ -0,0 +1,422 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"time"
+)
+
+// Sources producing a scan per line of text
+const (
+	SourceStdin = "stdin"
+	SourceFIFO  = "fifo"
+	SourceFile  = "file"
+)
+
+// How often a tailed file is checked for new lines and rotation
+const tailInterval = 250 * time.Millisecond
+
+// Line based sources deliver whole lines, unless a framing is configured
+// each line is a scan
+func lineOptions(options ScanOptions) ScanOptions {
+	if options.Framing == "" {
+		options.Framing = FramingMessage
+	}
+
+	return options
+}
+
+// Send a line to the assembler, as a whole scan with message framing or
+// followed by a line feed otherwise. Returns false if the context is done.
+func sendLine(ctx context.Context, characters chan input, line string, framing string) bool {
+	if framing == FramingMessage {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return true
+		}
+	} else if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	select {
+	case characters <- input{text: line}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Send each line read from r until it fails
+func readLines(ctx context.Context, r io.Reader, characters chan input, framing string) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if !sendLine(ctx, characters, scanner.Text(), framing) {
+			return ctx.Err()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// Reads a scan per line from the standard input
+type StdinReader struct {
+	DeviceID string
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+func (stdinReader *StdinReader) ID() string {
+	return stdinReader.DeviceID
+}
+
+func (stdinReader *StdinReader) readCharacters(ctx context.Context, characters chan input) {
+	framing := lineOptions(stdinReader.ScanOptions).Framing
+
+	stdinReader.setStatus(stdinReader.DeviceID, StatusConnected)
+
+	// Reads from the standard input cannot be interrupted, so they happen in
+	// their own goroutine which is left behind when the reader stops. Its
+	// lines are forwarded through a channel which is never closed, once the
+	// context is done it drops whatever it reads and ends with the input.
+	lines := make(chan input)
+	result := make(chan error, 1)
+	go func() {
+		result <- readLines(ctx, os.Stdin, lines, framing)
+	}()
+
+	var err error
+
+forward:
+	for {
+		select {
+		case line := <-lines:
+			select {
+			case characters <- line:
+			case <-ctx.Done():
+				return
+			}
+		case err = <-result:
+			break forward
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err == io.EOF {
+		stdinReader.logger.Info("End of standard input")
+	} else {
+		stdinReader.logger.Error("Error while reading standard input: %s", err)
+	}
+
+	stdinReader.setStatus(stdinReader.DeviceID, StatusDisconnected)
+}
+
+func (stdinReader *StdinReader) Run(ctx context.Context, scans chan<- Scan) {
+	if stdinReader.logger == nil {
+		stdinReader.logger = logging.GetLogger("READER:" + stdinReader.DeviceID)
+	}
+
+	options := lineOptions(stdinReader.ScanOptions)
+	scanAssembler := newAssembler(options, stdinReader.DeviceID, stdinReader.logger)
+	scanAssembler.run(ctx, stdinReader.readCharacters, scans)
+
+	stdinReader.setStatus(stdinReader.DeviceID, StatusStopped)
+}
+
+// Reads a scan per line written to a named pipe, created if missing. Any
+// number of writers can come and go.
+type FIFOReader struct {
+	DeviceID string
+	Path     string
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+func (fifoReader *FIFOReader) ID() string {
+	return fifoReader.DeviceID
+}
+
+func (fifoReader *FIFOReader) readCharacters(ctx context.Context, characters chan input) {
+	framing := lineOptions(fifoReader.ScanOptions).Framing
+
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	for {
+		fifo, err := openFIFO(fifoReader.Path)
+		if err != nil {
+			if err.Error() != lastError {
+				fifoReader.logger.Error("Cannot open %s: %s", fifoReader.Path, err)
+			}
+			lastError = err.Error()
+		} else {
+			lastError = ""
+
+			fifoReader.logger.Info("Pipe %s opened", fifoReader.Path)
+			fifoReader.setStatus(fifoReader.DeviceID, StatusConnected)
+
+			// Closing the pipe unblocks the pending read
+			stop := context.AfterFunc(ctx, func() { fifo.Close() })
+			err = readLines(ctx, fifo, characters, framing)
+			if stop() {
+				fifo.Close()
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			fifoReader.logger.Error("Lost pipe %s: %s", fifoReader.Path, err)
+		}
+
+		fifoReader.setStatus(fifoReader.DeviceID, StatusDisconnected)
+
+		select {
+		case <-time.After(pollingInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (fifoReader *FIFOReader) Run(ctx context.Context, scans chan<- Scan) {
+	if fifoReader.logger == nil {
+		fifoReader.logger = logging.GetLogger("READER:" + fifoReader.DeviceID)
+	}
+
+	options := lineOptions(fifoReader.ScanOptions)
+	scanAssembler := newAssembler(options, fifoReader.DeviceID, fifoReader.logger)
+	scanAssembler.run(ctx, fifoReader.readCharacters, scans)
+
+	fifoReader.setStatus(fifoReader.DeviceID, StatusStopped)
+}
+
+// Reads a scan per line appended to a file, like tail -F: only the lines
+// written after the start are read, and the file is reopened when it is
+// rotated or truncated.
+type TailReader struct {
+	DeviceID string
+	Path     string
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+func (tailReader *TailReader) ID() string {
+	return tailReader.DeviceID
+}
+
+// Send the complete lines in the buffer, returning what is left of it
+func (tailReader *TailReader) sendLines(ctx context.Context, buffer []byte, characters chan input, framing string) ([]byte, bool) {
+	for {
+		end := bytes.IndexByte(buffer, '\n')
+		if end < 0 {
+			return buffer, true
+		}
+
+		if !sendLine(ctx, characters, string(buffer[:end+1]), framing) {
+			return nil, false
+		}
+
+		buffer = buffer[end+1:]
+	}
+}
+
+func (tailReader *TailReader) readCharacters(ctx context.Context, characters chan input) {
+	framing := lineOptions(tailReader.ScanOptions).Framing
+
+	var file *os.File
+	var pending []byte
+
+	// Existing content is skipped only for the file found at startup, files
+	// created afterwards are read from the beginning
+	skipExisting := true
+
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	chunk := make([]byte, 4096)
+
+	for {
+		if file == nil {
+			opened, err := os.Open(tailReader.Path)
+			if err == nil {
+				if skipExisting {
+					_, err = opened.Seek(0, io.SeekEnd)
+				}
+
+				if err != nil {
+					opened.Close()
+				} else {
+					file = opened
+					pending = nil
+
+					tailReader.logger.Info("Following %s", tailReader.Path)
+					tailReader.setStatus(tailReader.DeviceID, StatusConnected)
+				}
+			}
+
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					tailReader.logger.Error("Cannot open %s: %s", tailReader.Path, err)
+				}
+
+				tailReader.setStatus(tailReader.DeviceID, StatusDisconnected)
+			}
+
+			skipExisting = false
+		}
+
+		if file != nil {
+			// Read everything appended since the last time
+			for {
+				n, err := file.Read(chunk)
+				if n > 0 {
+					var ok bool
+					pending, ok = tailReader.sendLines(ctx, append(pending, chunk[:n]...), characters, framing)
+					if !ok {
+						return
+					}
+				}
+
+				if err != nil {
+					break
+				}
+			}
+
+			if tailReader.rotated(file) {
+				tailReader.logger.Info("%s has been rotated", tailReader.Path)
+				file.Close()
+				file = nil
+				continue
+			}
+		}
+
+		select {
+		case <-time.After(tailInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Whether the open file has been replaced or truncated, the remaining lines
+// of a replaced file have already been read
+func (tailReader *TailReader) rotated(file *os.File) bool {
+	current, err := os.Stat(tailReader.Path)
+	if err != nil {
+		// Removed, wait for a new one to be created
+		return errors.Is(err, os.ErrNotExist)
+	}
+
+	opened, err := file.Stat()
+	if err != nil {
+		return true
+	}
+
+	if !os.SameFile(current, opened) {
+		return true
+	}
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return true
+	}
+
+	if current.Size() < offset {
+		// Truncated in place, start over from the beginning
+		file.Seek(0, io.SeekStart)
+	}
+
+	return false
+}
+
+func (tailReader *TailReader) Run(ctx context.Context, scans chan<- Scan) {
+	if tailReader.logger == nil {
+		tailReader.logger = logging.GetLogger("READER:" + tailReader.DeviceID)
+	}
+
+	options := lineOptions(tailReader.ScanOptions)
+	scanAssembler := newAssembler(options, tailReader.DeviceID, tailReader.logger)
+	scanAssembler.run(ctx, tailReader.readCharacters, scans)
+
+	tailReader.setStatus(tailReader.DeviceID, StatusStopped)
+}
+
+func newStdinSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	return &StdinReader{
+		DeviceID:    config.ID,
+		ScanOptions: options,
+	}, nil
+}
+
+func newFIFOSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Path == "" {
+		return nil, errors.New("the fifo type requires a path")
+	}
+
+	return &FIFOReader{
+		DeviceID:    config.ID,
+		Path:        config.Path,
+		ScanOptions: options,
+	}, nil
+}
+
+func newFileSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Path == "" {
+		return nil, errors.New("the file type requires a path")
+	}
+
+	return &TailReader{
+		DeviceID:    config.ID,
+		Path:        config.Path,
+		ScanOptions: options,
+	}, nil
+}
+
+func init() {
+	RegisterSource(SourceStdin, newStdinSource)
+	RegisterSource(SourceFIFO, newFIFOSource)
+	RegisterSource(SourceFile, newFileSource)
+}
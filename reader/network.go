@@ -0,0 +1,416 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scanners pushing their codes over the network
+const SourceNetwork = "network"
+
+const (
+	NetworkTCP = "tcp"
+	NetworkUDP = "udp"
+)
+
+// How the frames sent by network scanners are split
+const (
+	// Each frame ends with the delimiter
+	FrameDelimiter = "delimiter"
+	// Each frame starts with its length, as a big endian unsigned integer
+	FrameLength = "length"
+)
+
+// Longest frame accepted, anything longer is considered garbage
+const maxFrameLength = 64 * 1024
+
+// Build the split function for the frames, the trailing carriage return of
+// line feed delimited frames is stripped
+func frameSplitter(frame string, delimiter string, lengthBytes int) bufio.SplitFunc {
+	if frame == FrameLength {
+		return func(data []byte, atEOF bool) (int, []byte, error) {
+			if len(data) < lengthBytes {
+				if atEOF && len(data) > 0 {
+					return 0, nil, io.ErrUnexpectedEOF
+				}
+				return 0, nil, nil
+			}
+
+			var length uint64
+			switch lengthBytes {
+			case 1:
+				length = uint64(data[0])
+			case 2:
+				length = uint64(binary.BigEndian.Uint16(data))
+			default:
+				length = uint64(binary.BigEndian.Uint32(data))
+			}
+
+			if length > maxFrameLength {
+				return 0, nil, fmt.Errorf("frame of %d bytes is too long", length)
+			}
+
+			end := lengthBytes + int(length)
+			if len(data) < end {
+				if atEOF {
+					return 0, nil, io.ErrUnexpectedEOF
+				}
+				return 0, nil, nil
+			}
+
+			return end, data[lengthBytes:end], nil
+		}
+	}
+
+	separator := []byte(delimiter)
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, separator); i >= 0 {
+			token := data[:i]
+			if delimiter == "\n" {
+				token = bytes.TrimSuffix(token, []byte("\r"))
+			}
+
+			return i + len(separator), token, nil
+		}
+
+		if atEOF && len(data) > 0 {
+			// The last frame is not required to be terminated
+			return len(data), data, nil
+		}
+
+		return 0, nil, nil
+	}
+}
+
+// Listens for network scanners over TCP or UDP. Each remote address or
+// handshake token can be mapped to its own device id.
+type NetworkReader struct {
+	DeviceID    string // Used for the scans of unmapped clients
+	Network     string // NetworkTCP (default) or NetworkUDP
+	Address     string // e.g. :9100
+	Frame       string // FrameDelimiter (default) or FrameLength
+	Delimiter   string // A line feed if empty
+	LengthBytes int    // Size of the length prefix: 1, 2 (default) or 4
+	// Device ids by remote IP address
+	RemoteIDs map[string]string
+	// Device ids by handshake token, when set TCP clients must send one of
+	// the tokens as their first frame
+	Tokens map[string]string
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+func (networkReader *NetworkReader) ID() string {
+	return networkReader.DeviceID
+}
+
+func (networkReader *NetworkReader) splitter() bufio.SplitFunc {
+	delimiter := networkReader.Delimiter
+	if delimiter == "" {
+		delimiter = "\n"
+	}
+
+	lengthBytes := networkReader.LengthBytes
+	if lengthBytes == 0 {
+		lengthBytes = 2
+	}
+
+	return frameSplitter(networkReader.Frame, delimiter, lengthBytes)
+}
+
+// The device id of a client based on its address
+func (networkReader *NetworkReader) remoteID(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err == nil {
+		if id, ok := networkReader.RemoteIDs[host]; ok {
+			return id
+		}
+	}
+
+	return networkReader.DeviceID
+}
+
+// Each frame carries a whole scan
+func (networkReader *NetworkReader) newAssembler(deviceID string) *assembler {
+	options := networkReader.ScanOptions
+	options.Framing = FramingMessage
+
+	return newAssembler(options, deviceID, networkReader.logger)
+}
+
+func frameContent(frame []byte) string {
+	decoded, _ := decodeBytes(frame, nil)
+	return strings.Join(decoded, "")
+}
+
+func (networkReader *NetworkReader) handleConnection(ctx context.Context, conn net.Conn, scans chan<- Scan) {
+	// Closing the connection unblocks the pending read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() {
+		if stop() {
+			conn.Close()
+		}
+	}()
+
+	remote := conn.RemoteAddr().String()
+	deviceID := networkReader.remoteID(conn.RemoteAddr())
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxFrameLength+4)
+	scanner.Split(networkReader.splitter())
+
+	if len(networkReader.Tokens) > 0 {
+		// Wait a few seconds at most for the handshake
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if !scanner.Scan() {
+			networkReader.logger.Error("No handshake from %s", remote)
+			return
+		}
+
+		id, ok := networkReader.Tokens[string(scanner.Bytes())]
+		if !ok {
+			networkReader.logger.Error("Invalid handshake from %s", remote)
+			return
+		}
+
+		deviceID = id
+		conn.SetReadDeadline(time.Time{})
+	}
+
+	networkReader.logger.Info("Client %s connected as %s", remote, deviceID)
+
+	scanAssembler := networkReader.newAssembler(deviceID)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		scanAssembler.push(frameContent(scanner.Bytes()), time.Now(), scans)
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		networkReader.logger.Error("Client %s: %s", remote, err)
+	}
+
+	networkReader.logger.Info("Client %s disconnected", remote)
+}
+
+// Listen until the context is done, listening again right away when
+// accepting fails. The connections are handled in their own goroutines,
+// tracked by handlers, which keep running across listeners.
+func (networkReader *NetworkReader) listenTCP(ctx context.Context, scans chan<- Scan, handlers *sync.WaitGroup) error {
+	for {
+		var listenConfig net.ListenConfig
+
+		listener, err := listenConfig.Listen(ctx, "tcp", networkReader.Address)
+		if err != nil {
+			return err
+		}
+
+		err = networkReader.acceptTCP(ctx, listener, scans, handlers)
+		if ctx.Err() != nil {
+			return err
+		}
+
+		networkReader.logger.Error("Cannot accept on %s, listening again: %s", networkReader.Address, err)
+	}
+}
+
+// Accept connections until the listener fails or the context is done
+func (networkReader *NetworkReader) acceptTCP(ctx context.Context, listener net.Listener, scans chan<- Scan, handlers *sync.WaitGroup) error {
+	// Closing the listener unblocks the pending accept
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer func() {
+		if stop() {
+			listener.Close()
+		}
+	}()
+
+	networkReader.logger.Info("Listening on tcp %s", listener.Addr())
+	networkReader.setStatus(networkReader.DeviceID, StatusConnected)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+			networkReader.handleConnection(ctx, conn, scans)
+		}()
+	}
+}
+
+func (networkReader *NetworkReader) listenUDP(ctx context.Context, scans chan<- Scan) error {
+	var listenConfig net.ListenConfig
+
+	conn, err := listenConfig.ListenPacket(ctx, "udp", networkReader.Address)
+	if err != nil {
+		return err
+	}
+
+	// Closing the socket unblocks the pending read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() {
+		if stop() {
+			conn.Close()
+		}
+	}()
+
+	networkReader.logger.Info("Listening on udp %s", conn.LocalAddr())
+	networkReader.setStatus(networkReader.DeviceID, StatusConnected)
+
+	assemblers := map[string]*assembler{}
+	datagram := make([]byte, maxFrameLength)
+
+	for {
+		n, addr, err := conn.ReadFrom(datagram)
+		if err != nil {
+			return err
+		}
+
+		deviceID := networkReader.remoteID(addr)
+
+		scanAssembler, ok := assemblers[deviceID]
+		if !ok {
+			scanAssembler = networkReader.newAssembler(deviceID)
+			assemblers[deviceID] = scanAssembler
+		}
+
+		// A datagram can carry more than one frame
+		scanner := bufio.NewScanner(bytes.NewReader(datagram[:n]))
+		scanner.Buffer(make([]byte, 0, n), maxFrameLength+4)
+		scanner.Split(networkReader.splitter())
+
+		for scanner.Scan() {
+			if len(scanner.Bytes()) > 0 {
+				scanAssembler.push(frameContent(scanner.Bytes()), time.Now(), scans)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			networkReader.logger.Error("Invalid datagram from %s: %s", addr, err)
+		}
+	}
+}
+
+func (networkReader *NetworkReader) Run(ctx context.Context, scans chan<- Scan) {
+	if networkReader.logger == nil {
+		networkReader.logger = logging.GetLogger("READER:" + networkReader.DeviceID)
+	}
+
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	// The tcp connections still open, waited for when stopping so that no
+	// scan is sent once the reader has returned
+	var handlers sync.WaitGroup
+
+	for {
+		var err error
+		if networkReader.Network == NetworkUDP {
+			err = networkReader.listenUDP(ctx, scans)
+		} else {
+			err = networkReader.listenTCP(ctx, scans, &handlers)
+		}
+
+		if ctx.Err() != nil {
+			handlers.Wait()
+
+			networkReader.logger.Info("Stopping network reader: %s", networkReader.DeviceID)
+			networkReader.setStatus(networkReader.DeviceID, StatusStopped)
+			return
+		}
+
+		if err.Error() != lastError {
+			networkReader.logger.Error("Cannot listen on %s: %s", networkReader.Address, err)
+		}
+		lastError = err.Error()
+
+		networkReader.setStatus(networkReader.DeviceID, StatusDisconnected)
+
+		select {
+		case <-time.After(pollingInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func newNetworkSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Address == "" {
+		return nil, errors.New("the network type requires an address")
+	}
+
+	switch config.Network {
+	case "", NetworkTCP:
+	case NetworkUDP:
+		if len(config.Tokens) > 0 {
+			return nil, errors.New("handshake tokens are only supported over tcp")
+		}
+	default:
+		return nil, fmt.Errorf("unknown network '%s'", config.Network)
+	}
+
+	switch config.Frame {
+	case "", FrameDelimiter:
+	case FrameLength:
+		switch config.LengthBytes {
+		case 0, 1, 2, 4:
+		default:
+			return nil, fmt.Errorf("unsupported length_bytes %d", config.LengthBytes)
+		}
+	default:
+		return nil, fmt.Errorf("unknown frame '%s'", config.Frame)
+	}
+
+	return &NetworkReader{
+		DeviceID:    config.ID,
+		Network:     config.Network,
+		Address:     config.Address,
+		Frame:       config.Frame,
+		Delimiter:   config.Delimiter,
+		LengthBytes: config.LengthBytes,
+		RemoteIDs:   config.RemoteIDs,
+		Tokens:      config.Tokens,
+		ScanOptions: options,
+	}, nil
+}
+
+func init() {
+	RegisterSource(SourceNetwork, newNetworkSource)
+}
@@ -0,0 +1,193 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// The names of the virtual keyboards created by the relay start with this,
+// devices with this prefix are never read
+const VirtualDevicePrefix = "Barcode relay "
+
+// The name of the virtual keyboards created by passthrough mode
+const PassthroughDeviceName = VirtualDevicePrefix + "passthrough"
+
+// Defaults of the passthrough settings
+const (
+	DefaultPassthroughGap       = 30 * time.Millisecond
+	DefaultPassthroughMinLength = 4
+)
+
+// How the scans are told apart from the keystrokes of a typist on a device
+// shared with a keyboard. Scanners type much faster than people: a burst of
+// at least MinLength key presses, each within MaxGap of the previous one,
+// is a scan, and so is a burst starting with the frame prefix when the
+// delimited framing is used. Everything else is sent back to the system.
+type PassthroughOptions struct {
+	MaxGap    time.Duration
+	MinLength int
+}
+
+// Splits the events of a device into scans and keystrokes to send back
+type passthrough struct {
+	PassthroughOptions
+	prefix string // The frame prefix, if any
+
+	keyboard *Keyboard
+	sink     EventSink
+	send     func(character string) // Sends a character of a scan
+
+	mutex sync.Mutex
+	timer *time.Timer
+
+	// The events of the burst being collected and its key presses
+	pending   []InputEvent
+	presses   int
+	lastPress int64
+
+	// Keys pressed during a scan, whose releases are not sent back either
+	swallowed map[uint16]bool
+
+	closed bool
+}
+
+func newPassthrough(options PassthroughOptions, prefix string, keyboard *Keyboard, sink EventSink, send func(string)) *passthrough {
+	passthrough := &passthrough{
+		PassthroughOptions: options,
+		prefix:             prefix,
+		keyboard:           keyboard,
+		sink:               sink,
+		send:               send,
+		swallowed:          map[uint16]bool{},
+	}
+
+	// The burst is over once no key has been pressed for the max gap
+	passthrough.timer = time.AfterFunc(time.Hour, passthrough.flush)
+	passthrough.timer.Stop()
+
+	return passthrough
+}
+
+// Handle an event read from the device
+func (passthrough *passthrough) handle(event InputEvent) {
+	passthrough.mutex.Lock()
+	defer passthrough.mutex.Unlock()
+
+	if event.Type == EventKey && event.Value == 1 {
+		maxGap := passthrough.MaxGap.Microseconds()
+		if len(passthrough.pending) > 0 && event.Time-passthrough.lastPress > maxGap {
+			passthrough.flushLocked()
+		}
+
+		passthrough.pending = append(passthrough.pending, event)
+		passthrough.lastPress = event.Time
+		if !isModifier(event.Code) {
+			passthrough.presses++
+		}
+
+		passthrough.timer.Reset(passthrough.MaxGap)
+		return
+	}
+
+	if len(passthrough.pending) > 0 {
+		// Keep the order of the events within the burst
+		passthrough.pending = append(passthrough.pending, event)
+		return
+	}
+
+	// Track the modifiers of the typist too
+	passthrough.keyboard.HandleEvent(event.Type, event.Code, event.Value)
+
+	if event.Type == EventKey && passthrough.swallowed[event.Code] {
+		if event.Value == 0 {
+			delete(passthrough.swallowed, event.Code)
+		}
+		return
+	}
+
+	passthrough.sink.WriteEvent(event)
+}
+
+func (passthrough *passthrough) flush() {
+	passthrough.mutex.Lock()
+	defer passthrough.mutex.Unlock()
+
+	if !passthrough.closed {
+		passthrough.flushLocked()
+	}
+}
+
+// Decide whether the pending burst is a scan, sending its characters if so
+// or its events back to the system otherwise
+func (passthrough *passthrough) flushLocked() {
+	passthrough.timer.Stop()
+
+	if len(passthrough.pending) == 0 {
+		return
+	}
+
+	// The characters are sent one keystroke at a time, as the framing expects
+	var characters []string
+	var decoded strings.Builder
+
+	for _, event := range passthrough.pending {
+		character := passthrough.keyboard.HandleEvent(event.Type, event.Code, event.Value)
+		if character != "" {
+			characters = append(characters, character)
+			decoded.WriteString(character)
+		}
+	}
+
+	isScan := passthrough.presses >= passthrough.MinLength ||
+		(passthrough.prefix != "" && strings.HasPrefix(decoded.String(), passthrough.prefix))
+
+	if isScan {
+		for _, event := range passthrough.pending {
+			if event.Type == EventKey && event.Value == 1 {
+				passthrough.swallowed[event.Code] = true
+			} else if event.Type == EventKey && event.Value == 0 {
+				delete(passthrough.swallowed, event.Code)
+			}
+		}
+
+		for _, character := range characters {
+			passthrough.send(character)
+		}
+	} else {
+		for _, event := range passthrough.pending {
+			passthrough.sink.WriteEvent(event)
+		}
+	}
+
+	passthrough.pending = nil
+	passthrough.presses = 0
+}
+
+// Stop the passthrough and remove the virtual keyboard, a pending burst is
+// dropped since the device is gone or the reader is stopping
+func (passthrough *passthrough) close() {
+	passthrough.mutex.Lock()
+	defer passthrough.mutex.Unlock()
+
+	passthrough.timer.Stop()
+	passthrough.closed = true
+	passthrough.sink.Close()
+}
@@ -21,209 +21,570 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"sirafino/go-barcode-relay/logging"
-	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/holoplot/go-evdev"
 )
 
-func ListDevices() ([]string, error) {
-	var devices []*evdev.InputDevice = make([]*evdev.InputDevice, 0)
-	var results []string = make([]string, 0)
-
-	paths, error := evdev.ListDevicePaths()
-	if error != nil {
-		return results, error
+// Describe the input devices. With probe set, devices are also reported as
+// grabbed when another process (e.g. a running relay) holds them, found by
+// trying to grab each device and releasing it right away, which loses the
+// keys typed in the meantime. Otherwise only the grabs of this process are
+// reported.
+func DescribeDevices(probe bool) ([]DeviceDescriptor, error) {
+	paths, err := evdev.ListDevicePaths()
+	if err != nil {
+		return nil, err
 	}
 
+	descriptors := []DeviceDescriptor{}
+
 	for _, path := range paths {
-		device, error := evdev.Open(path.Path)
-		if error != nil {
-			// TODO: maybe give some warning here?
+		device, err := evdev.Open(path.Path)
+		if err != nil {
 			continue
 		}
 
-		evtypes := device.CapableTypes()
-		if !slices.Contains(evtypes, evdev.EV_KEY) {
-			// Skip device if it cannot send "EV_KEY" events
+		info, err := GetDeviceInfo(device)
+		if err != nil {
+			device.Close()
 			continue
 		}
 
-		devices = append(devices, device)
+		descriptor := newDeviceDescriptor(info, capabilityNames(device))
+		if probe && !descriptor.Grabbed {
+			descriptor.Grabbed = grabbedElsewhere(device)
+		}
+
+		device.Close()
+
+		descriptors = append(descriptors, descriptor)
 	}
 
-	for i, device := range devices {
-		name, error := device.Name()
-		if error != nil {
-			name = ""
-		}
+	return descriptors, nil
+}
+
+// The event types a device can send, e.g. EV_KEY
+func capabilityNames(device *evdev.InputDevice) []string {
+	capabilities := []string{}
+	for _, evType := range device.CapableTypes() {
+		capabilities = append(capabilities, evdev.TypeName(evType))
+	}
 
-		path := device.Path()
+	return capabilities
+}
 
-		ids, error := device.InputID()
-		if error != nil {
-			// TODO:
-			continue
+// Describe an open device, only evdev devices have more than a path
+func describeDevice(device EventDevice) DeviceDescriptor {
+	if source, ok := device.(*evdevDevice); ok {
+		if info, err := GetDeviceInfo(source.device); err == nil {
+			return newDeviceDescriptor(info, capabilityNames(source.device))
 		}
+	}
+
+	return DeviceDescriptor{Path: device.Path()}
+}
 
-		results = append(
-			results,
-			fmt.Sprintf("%d - %s - %s - VID %04d - PID %04d\n", i, name, path, ids.Vendor, ids.Product),
-		)
+// Whether another process holds the exclusive grab of the device. The evdev
+// library only reports the error message, compared against EBUSY's.
+func grabbedElsewhere(device *evdev.InputDevice) bool {
+	err := device.Grab()
+	if err == nil {
+		device.Ungrab()
+		return false
 	}
 
-	return results, nil
+	return err.Error() == syscall.EBUSY.Error()
 }
 
-func FindDeviceByIDs(vid uint16, pid uint16) (*evdev.InputDevice, error) {
-	paths, error := evdev.ListDevicePaths()
-	if error != nil {
-		return nil, error
+// Read the identification data of an evdev device
+func GetDeviceInfo(device *evdev.InputDevice) (*DeviceInfo, error) {
+	ids, err := device.InputID()
+	if err != nil {
+		return nil, err
 	}
 
+	// Name, physical location and unique id are optional
+	name, _ := device.Name()
+	phys, _ := device.PhysicalLocation()
+	uniq, _ := device.UniqueID()
+
+	return &DeviceInfo{
+		Path: device.Path(),
+		Name: name,
+		Phys: phys,
+		Uniq: uniq,
+		VID:  ids.Vendor,
+		PID:  ids.Product,
+		Bus:  ids.BusType,
+		HWID: fmt.Sprintf(
+			"VID_%04X&PID_%04X&BUS_%04X&NAME_%s&PHYS_%s&UNIQ_%s",
+			ids.Vendor, ids.Product, ids.BusType, name, phys, uniq,
+		),
+		Keyboard: canType(device),
+	}, nil
+}
+
+// Whether the device sends key events and has enter or the digit keys, which
+// mouse buttons and media keys do not
+func canType(device *evdev.InputDevice) bool {
+	for _, code := range device.CapableEvents(evdev.EV_KEY) {
+		if code == evdev.EvCode(KeyEnter) || (code >= 2 && code <= 11) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// List the identification data of all the devices, except the virtual
+// keyboards of the relay
+func listDevices() ([]*DeviceInfo, error) {
+	paths, err := evdev.ListDevicePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []*DeviceInfo{}
+
 	for _, path := range paths {
-		device, error := evdev.Open(path.Path)
-		if error != nil {
+		device, err := evdev.Open(path.Path)
+		if err != nil {
 			// TODO: maybe give some warning here?
 			continue
 		}
 
-		ids, error := device.InputID()
-		if error != nil {
-			// TODO:
+		info, err := GetDeviceInfo(device)
+		device.Close()
+
+		if err != nil || strings.HasPrefix(info.Name, VirtualDevicePrefix) {
+			// The virtual keyboards of the relay must never be read
 			continue
 		}
 
-		if ids.Vendor == vid && ids.Product == pid {
-			return device, nil
-		}
+		devices = append(devices, info)
+	}
+
+	return devices, nil
+}
+
+// List the identification data of all the keyboards matching the selector
+func ListMatchingDevices(selector *DeviceSelector) ([]*DeviceInfo, error) {
+	devices, err := listDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	return MatchingKeyboards(selector, devices), nil
+}
+
+// Find and open the keyboard matching the selector, failing if more than
+// one keyboard matches it
+func FindDevice(selector *DeviceSelector) (*evdev.InputDevice, error) {
+	devices, err := listDevices()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, errors.New("notfound")
+	info, err := SelectDevice(selector, devices)
+	if err != nil {
+		return nil, err
+	}
+
+	return evdev.Open(info.Path)
 }
 
+// Virtual keyboards are created through /dev/uinput, for passthrough and
+// the keyboard target
+const VirtualKeyboardSupported = true
+
 type DeviceReader struct {
-	DeviceID    string
-	VID         uint16
-	PID         uint16
-	Regex       *regexp.Regexp
-	evdevDevice *evdev.InputDevice
+	DeviceID string
+	Selector DeviceSelector
+	Layout   *Layout
+	AltCodes string // How Alt+numpad codes are decoded (AltCodesCP437 by default)
+	ScanOptions
+
+	// Where the device is opened from, evdev if nil
+	Events EventSource
+
+	// Send the keystrokes which are not part of a scan back to the system,
+	// nil disables passthrough mode
+	Passthrough *PassthroughOptions
+
+	device      EventDevice
 	grabbed     bool
-	buffer      string
+	unwatch     func() bool // Stops the device from being closed with the context
+	passthrough *passthrough
+	keyboard    Keyboard
 	logger      *logging.Logger
+	sourceState
+}
+
+// Opens evdev devices, watching /dev/input for changes
+type evdevEvents struct{}
+
+func (evdevEvents) Open(selector *DeviceSelector) (EventDevice, error) {
+	device, err := FindDevice(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return &evdevDevice{device: device}, nil
+}
+
+func (evdevEvents) Watch(ctx context.Context) <-chan struct{} {
+	return watchDevices(ctx)
+}
+
+func (evdevEvents) List(selector *DeviceSelector) ([]*DeviceInfo, error) {
+	return ListMatchingDevices(selector)
+}
+
+// Create a virtual keyboard with the key capabilities of the device. Its ids
+// are left empty and it has its own name, so that no selector matches it.
+func (evdevEvents) Mirror(device EventDevice) (EventSink, error) {
+	source, ok := device.(*evdevDevice)
+	if !ok {
+		return nil, errors.New("not an evdev device")
+	}
+
+	capabilities := map[evdev.EvType][]evdev.EvCode{}
+	for _, evType := range []evdev.EvType{evdev.EV_KEY, evdev.EV_MSC, evdev.EV_LED} {
+		if codes := source.device.CapableEvents(evType); len(codes) > 0 {
+			capabilities[evType] = codes
+		}
+	}
+
+	name, _ := source.device.Name()
+
+	mirror, err := evdev.CreateDevice(
+		fmt.Sprintf("%s (%s)", PassthroughDeviceName, name),
+		evdev.InputID{BusType: evdev.BUS_VIRTUAL},
+		capabilities,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &uinputSink{device: mirror}, nil
+}
+
+// Create a virtual keyboard able to press the given keys, whose name should
+// start with VirtualDevicePrefix
+func NewVirtualKeyboard(name string, codes []uint16) (EventSink, error) {
+	keys := make([]evdev.EvCode, len(codes))
+	for i, code := range codes {
+		keys[i] = evdev.EvCode(code)
+	}
+
+	keyboard, err := evdev.CreateDevice(
+		name,
+		evdev.InputID{BusType: evdev.BUS_VIRTUAL},
+		map[evdev.EvType][]evdev.EvCode{evdev.EV_KEY: keys},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &uinputSink{device: keyboard}, nil
+}
+
+// A virtual keyboard created through /dev/uinput
+type uinputSink struct {
+	device *evdev.InputDevice
+}
+
+func (sink *uinputSink) WriteEvent(event InputEvent) error {
+	// The kernel sets the time of the events
+	return sink.device.WriteOne(&evdev.InputEvent{
+		Type:  evdev.EvType(event.Type),
+		Code:  evdev.EvCode(event.Code),
+		Value: event.Value,
+	})
+}
+
+func (sink *uinputSink) Close() error {
+	evdev.DestroyDevice(sink.device)
+	return sink.device.Close()
+}
+
+type evdevDevice struct {
+	device    *evdev.InputDevice
+	closeOnce sync.Once
+}
+
+func (device *evdevDevice) Grab() error {
+	return device.device.Grab()
+}
+
+func (device *evdevDevice) ReadEvent() (InputEvent, error) {
+	event, err := device.device.ReadOne()
+	if err != nil {
+		return InputEvent{}, err
+	}
+
+	return InputEvent{
+		Time:  int64(event.Time.Sec)*1000000 + int64(event.Time.Usec),
+		Type:  uint16(event.Type),
+		Code:  uint16(event.Code),
+		Value: event.Value,
+	}, nil
+}
+
+func (device *evdevDevice) Path() string {
+	return device.device.Path()
+}
+
+// Release the grab and close the device, only the first call has effect
+func (device *evdevDevice) Close() error {
+	var err error
+
+	device.closeOnce.Do(func() {
+		// The kernel releases the grab on close as well, but only once every
+		// copy of the file descriptor is closed
+		device.device.Ungrab()
+		err = device.device.Close()
+	})
+
+	return err
 }
 
 func (deviceReader *DeviceReader) Reset() {
-	deviceReader.evdevDevice = nil
+	if deviceReader.passthrough != nil {
+		deviceReader.passthrough.close()
+		deviceReader.passthrough = nil
+	}
+
+	if deviceReader.device != nil {
+		deviceReader.unwatch()
+
+		if deviceReader.grabbed {
+			releaseGrabbed(deviceReader.device.Path())
+		}
+
+		deviceReader.device.Close()
+	}
+
+	deviceReader.device = nil
 	deviceReader.grabbed = false
-	deviceReader.buffer = ""
+	deviceReader.keyboard.Reset()
 }
 
-func (deviceReader *DeviceReader) readCharacter() (*string, error) {
-	event, err := deviceReader.evdevDevice.ReadOne()
+// Read the next event, returning the character it completes along with the
+// time of the event, if any
+func (deviceReader *DeviceReader) readCharacter() (*input, error) {
+	event, err := deviceReader.device.ReadEvent()
 
 	if err != nil {
-		deviceReader.logger.Info("Device disconnected\n")
-		deviceReader.Reset()
-		return nil, fmt.Errorf("disconnected")
+		return nil, err
 	}
 
-	if event.Type != evdev.EV_KEY {
-		// Don't care about other events
+	if deviceReader.passthrough != nil {
+		// Scans are sent by the passthrough once their burst is over
+		deviceReader.passthrough.handle(event)
 		return nil, nil
 	}
 
-	if event.Value != 1 {
-		// Not a keydown event
+	character := deviceReader.keyboard.HandleEvent(event.Type, event.Code, event.Value)
+	if character == "" {
 		return nil, nil
 	}
 
-	code := event.Code
+	// The time the key was pressed, not when it is read: the characters of a
+	// reader lagging behind would otherwise all come in a burst
+	var at time.Time
+	if event.Time != 0 {
+		at = time.UnixMicro(event.Time)
+	}
+
+	return &input{text: character, at: at}, nil
+}
 
-	character := CharMap[(uint16)(code)]
+// Create the virtual keyboard the keystrokes of the grabbed device which are
+// not part of a scan are sent back through
+func (deviceReader *DeviceReader) startPassthrough(ctx context.Context, events EventSource, characters chan input) error {
+	sink, err := events.Mirror(deviceReader.device)
+	if err != nil {
+		return err
+	}
 
-	return &character, nil
+	prefix := ""
+	if deviceReader.Framing == FramingDelimited {
+		prefix = deviceReader.Prefix
+	}
+
+	send := func(scan string) {
+		select {
+		case characters <- input{text: scan}:
+		case <-ctx.Done():
+		}
+	}
+
+	deviceReader.passthrough = newPassthrough(*deviceReader.Passthrough, prefix, &deviceReader.keyboard, sink, send)
+	return nil
 }
 
-func (deviceReader *DeviceReader) readCharacters(characters chan string, polling_ms int16) {
+func (deviceReader *DeviceReader) readCharacters(ctx context.Context, characters chan input) {
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	events := deviceReader.Events
+	if events == nil {
+		events = evdevEvents{}
+	}
+
+	// Signaled when devices are plugged or unplugged
+	changes := events.Watch(ctx)
+
+	// Release the device once stopped
+	defer deviceReader.Reset()
+
 	for {
-		if deviceReader.evdevDevice == nil {
-			evdevDevice, error := FindDeviceByIDs(deviceReader.VID, deviceReader.PID)
-			if error != nil {
-				time.Sleep(time.Duration(polling_ms) * time.Millisecond)
+		if ctx.Err() != nil {
+			// The reader has been stopped
+			return
+		}
+
+		if deviceReader.device == nil {
+			device, err := events.Open(&deviceReader.Selector)
+			if err != nil {
+				if errors.Is(err, ErrAmbiguousDevice) && err.Error() != lastError {
+					deviceReader.logger.Error("Cannot select device: %s", err)
+				}
+				lastError = err.Error()
+
+				// Wait for the device to be plugged in
+				select {
+				case <-changes:
+				case <-ctx.Done():
+					return
+				}
 				continue
 			}
+			lastError = ""
 
 			deviceReader.logger.Info("Device connected\n")
 			deviceReader.Reset()
-			deviceReader.evdevDevice = evdevDevice
+			deviceReader.device = device
+
+			// Closing the device unblocks the pending read
+			deviceReader.unwatch = context.AfterFunc(ctx, func() { device.Close() })
 		}
 
 		if !deviceReader.grabbed {
-			error := deviceReader.evdevDevice.Grab()
-			if error != nil {
-				deviceReader.logger.Error("Error while grabbing device, trying again in %d ms\n", polling_ms)
+			err := deviceReader.device.Grab()
+			if err != nil {
+				deviceReader.logger.Error("Error while grabbing device, trying again in %d ms\n", pollingInterval.Milliseconds())
 				deviceReader.Reset()
-				time.Sleep(time.Duration(polling_ms) * time.Millisecond)
+
+				select {
+				case <-time.After(pollingInterval):
+				case <-ctx.Done():
+					return
+				}
 				continue
 			}
 
 			deviceReader.grabbed = true
+			setGrabbed(describeDevice(deviceReader.device), deviceReader.DeviceID)
+
+			if deviceReader.Passthrough != nil {
+				err = deviceReader.startPassthrough(ctx, events, characters)
+				if err != nil {
+					deviceReader.logger.Error("Cannot create the passthrough keyboard, trying again in %d ms: %s", pollingInterval.Milliseconds(), err)
+					deviceReader.Reset()
+
+					select {
+					case <-time.After(pollingInterval):
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+			}
+
+			deviceReader.setStatus(deviceReader.DeviceID, StatusConnected)
 		}
 
 		for {
 			character, err := deviceReader.readCharacter()
 			if err != nil {
+				if ctx.Err() != nil {
+					// The device has been closed to stop the reader
+					return
+				}
+
+				deviceReader.logger.Info("Device disconnected\n")
+				deviceReader.Reset()
+
+				// The partial scan of the device must not be glued to the
+				// first scan after it is plugged again
+				select {
+				case characters <- input{reset: true}:
+				case <-ctx.Done():
+					return
+				}
+
+				deviceReader.setStatus(deviceReader.DeviceID, StatusDisconnected)
 				break
 			}
 
 			if character != nil {
-				characters <- *character
+				select {
+				case characters <- *character:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}
 }
 
-func (deviceReader *DeviceReader) Run(
-	ctx context.Context,
-	scans chan Scan,
-	polling_ms int16,
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
+// Build the reader of a device found by a spawner
+func (spawner *DeviceSpawner) newReader(id string, selector DeviceSelector) *DeviceReader {
+	return &DeviceReader{
+		DeviceID:    id,
+		Selector:    selector,
+		Layout:      spawner.Layout,
+		AltCodes:    spawner.AltCodes,
+		ScanOptions: spawner.ScanOptions,
+		Passthrough: spawner.Passthrough,
+		Events:      spawner.Events,
+	}
+}
 
+// Build the reader playing back a recording
+func newReplayReader(id string, layout *Layout, altCodes string, options ScanOptions, passthrough *PassthroughOptions, events *ReplayEvents) (Source, error) {
+	return &DeviceReader{
+		DeviceID:    id,
+		Layout:      layout,
+		AltCodes:    altCodes,
+		ScanOptions: options,
+		Passthrough: passthrough,
+		Events:      events,
+	}, nil
+}
+
+func (deviceReader *DeviceReader) Run(ctx context.Context, scans chan<- Scan) {
 	if deviceReader.logger == nil {
 		deviceReader.logger = logging.GetLogger("READER:" + deviceReader.DeviceID)
 	}
 
-	characters := make(chan string, 1)
+	deviceReader.keyboard.Layout = deviceReader.Layout
+	deviceReader.keyboard.AltCodes = deviceReader.AltCodes
 
-	go deviceReader.readCharacters(characters, polling_ms)
+	// Assemble the characters into scans until the context is done
+	scanAssembler := newAssembler(deviceReader.ScanOptions, deviceReader.DeviceID, deviceReader.logger)
+	scanAssembler.run(ctx, deviceReader.readCharacters, scans)
 
-	for {
-		select {
-		case <-ctx.Done():
-			deviceReader.logger.Info("Stopping device reader: %s", deviceReader.DeviceID)
-			return
-		case character := <-characters:
-			deviceReader.buffer += character
-
-			if deviceReader.Regex.Match([]byte(deviceReader.buffer)) {
-				scan := Scan{
-					DeviceID:  deviceReader.DeviceID,
-					Content:   deviceReader.buffer,
-					Timestamp: time.Now().Unix(),
-				}
-
-				deviceReader.logger.Info("Read scan (%s)", strings.ReplaceAll(scan.Content, "\n", ""))
+	deviceReader.setStatus(deviceReader.DeviceID, StatusStopped)
+}
 
-				scans <- scan
-				deviceReader.buffer = ""
-			}
-		}
-	}
+func (deviceReader *DeviceReader) ID() string {
+	return deviceReader.DeviceID
 }
@@ -21,19 +21,22 @@ package reader
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"regexp"
 	"sirafino/go-barcode-relay/interception"
 	"sirafino/go-barcode-relay/logging"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"golang.org/x/sys/windows"
 )
 
-// ListDevices retrieves a list of active input devices (keyboards/mice)
-func ListDevices() ([]string, error) {
-	var results []string = make([]string, 0)
+// Describe the keyboards. With probe set, keyboards are also reported as
+// grabbed when another process (e.g. a running relay) filters their events.
+func DescribeDevices(probe bool) ([]DeviceDescriptor, error) {
+	descriptors := []DeviceDescriptor{}
 
 	for i := range interception.MaxDevices {
 		if !interception.IsKeyboard(i) {
@@ -47,28 +50,200 @@ func ListDevices() ([]string, error) {
 
 		hwid, err := device.GetHWID()
 		if err != nil {
+			device.Close()
 			continue
 		}
 
-		results = append(results, hwid)
+		descriptor := newDeviceDescriptor(getDeviceInfo(i, hwid), []string{"EV_KEY"})
+		if probe && !descriptor.Grabbed {
+			filter, err := device.GetFilter()
+			descriptor.Grabbed = err == nil && *filter != 0
+		}
+
+		device.Close()
+
+		descriptors = append(descriptors, descriptor)
+	}
+
+	return descriptors, nil
+}
+
+// The path of a device, interception devices are only known by their index
+func interceptionPath(index int) string {
+	return fmt.Sprintf("interception%02d", index)
+}
+
+var (
+	vidRegex = regexp.MustCompile(`VID_([0-9A-Fa-f]{4})`)
+	pidRegex = regexp.MustCompile(`PID_([0-9A-Fa-f]{4})`)
+)
+
+// Build the identification data of a device from its hardware id, name,
+// physical location and unique id are not available on Windows
+func getDeviceInfo(index int, hwid string) *DeviceInfo {
+	info := DeviceInfo{
+		Path: interceptionPath(index),
+		HWID: hwid,
+		// Only keyboards are reported by the Interception driver
+		Keyboard: true,
+	}
+
+	if match := vidRegex.FindStringSubmatch(hwid); match != nil {
+		vid, _ := strconv.ParseUint(match[1], 16, 16)
+		info.VID = uint16(vid)
+	}
+
+	if match := pidRegex.FindStringSubmatch(hwid); match != nil {
+		pid, _ := strconv.ParseUint(match[1], 16, 16)
+		info.PID = uint16(pid)
+	}
+
+	return &info
+}
+
+// List the identification data of all the keyboards matching the selector
+func ListMatchingDevices(selector *DeviceSelector) ([]*DeviceInfo, error) {
+	matches := []*DeviceInfo{}
+
+	for i := range interception.MaxDevices {
+		if !interception.IsKeyboard(i) {
+			continue
+		}
+
+		device, err := interception.NewDevice(i)
+		if err != nil {
+			continue
+		}
+
+		hwid, err := device.GetHWID()
+		device.Close()
+
+		if err != nil {
+			continue
+		}
+
+		info := getDeviceInfo(i, hwid)
+		if selector.Match(info) {
+			matches = append(matches, info)
+		}
 	}
 
-	return results, nil
+	return matches, nil
+}
+
+// Find the keyboard matching the selector, failing if more than one device
+// matches it. Devices not returned are closed.
+func FindDevice(selector *DeviceSelector) (*interception.Device, error) {
+	var found *interception.Device
+	matches := []string{}
+
+	for i := range interception.MaxDevices {
+		if !interception.IsKeyboard(i) {
+			continue
+		}
+
+		device, err := interception.NewDevice(i)
+		if err != nil {
+			continue
+		}
+
+		hwid, err := device.GetHWID()
+		if err != nil || !selector.Match(getDeviceInfo(i, hwid)) {
+			device.Close()
+			continue
+		}
+
+		matches = append(matches, hwid)
+
+		if found == nil {
+			found = device
+		} else {
+			device.Close()
+		}
+	}
+
+	if found == nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	if len(matches) > 1 {
+		found.Close()
+		return nil, fmt.Errorf("%w (%s)", ErrAmbiguousDevice, strings.Join(matches, ", "))
+	}
+
+	err := found.Init()
+	if err != nil {
+		found.Close()
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// Return a channel signaled periodically until the context is done, devices
+// are polled since the Interception driver has no hotplug notifications
+func watchDevices(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(pollingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
+// The Interception driver cannot send keystrokes back to the system, so
+// neither passthrough nor the keyboard target are available
+const VirtualKeyboardSupported = false
+
+// Virtual keyboards need uinput, see VirtualKeyboardSupported
+func NewVirtualKeyboard(name string, codes []uint16) (EventSink, error) {
+	return nil, errors.New("virtual keyboards are only supported on Linux")
 }
 
 type DeviceReader struct {
 	DeviceID string
-	VID      uint16
-	PID      uint16
-	Regex    *regexp.Regexp
+	Selector DeviceSelector
+	Layout   *Layout
+	AltCodes string // How Alt+numpad codes are decoded (AltCodesCP437 by default)
+	ScanOptions
+
+	// Always nil, see VirtualKeyboardSupported
+	Passthrough *PassthroughOptions
+
 	device   *interception.Device
-	buffer   string
+	keyboard Keyboard
 	logger   *logging.Logger
+	sourceState
+}
+
+// Scan codes sent with the E0 prefix, mapped to their key codes
+var extendedKeys = map[uint16]uint16{
+	0x1C: KeyKPEnter,
+	0x1D: KeyRightCtrl,
+	0x35: KeyKPSlash,
+	0x38: KeyRightAlt,
 }
 
 func (deviceReader *DeviceReader) findDevice() bool {
-	device, err := interception.FindDeviceByIDs(deviceReader.VID, deviceReader.PID)
+	device, err := FindDevice(&deviceReader.Selector)
 	if err != nil {
+		if errors.Is(err, ErrAmbiguousDevice) {
+			deviceReader.logger.Error("Cannot select device: %s", err)
+		}
 		return false
 	}
 
@@ -77,31 +252,65 @@ func (deviceReader *DeviceReader) findDevice() bool {
 	err = device.SetFilter(interception.INTERCEPTION_FILTER_KEY_ALL)
 	if err != nil {
 		deviceReader.logger.Error("Unable to set filter for device: %s", err)
+		device.Close()
 		return false
 	}
 
 	deviceReader.device = device
+
+	descriptor := DeviceDescriptor{Path: interceptionPath(device.Index())}
+	if hwid, err := device.GetHWID(); err == nil {
+		descriptor = newDeviceDescriptor(getDeviceInfo(device.Index(), hwid), []string{"EV_KEY"})
+	}
+	setGrabbed(descriptor, deviceReader.DeviceID)
 	return true
 }
 
-func (deviceReader *DeviceReader) readCharacters(characters chan string, polling_ms int16) {
+// Stop filtering the keystrokes of the device, so that they reach the rest
+// of the system again, and close it
+func (deviceReader *DeviceReader) releaseDevice() {
+	if deviceReader.device == nil {
+		return
+	}
+
+	deviceReader.device.SetFilter(interception.INTERCEPTION_FILTER_KEY_NONE)
+	releaseGrabbed(interceptionPath(deviceReader.device.Index()))
+	deviceReader.device.Close()
+	deviceReader.device = nil
+	deviceReader.keyboard.Reset()
+}
+
+func (deviceReader *DeviceReader) readCharacters(ctx context.Context, characters chan input) {
+	// Release the device once stopped
+	defer deviceReader.releaseDevice()
+
 	for {
+		if ctx.Err() != nil {
+			// The reader has been stopped
+			return
+		}
+
 		if deviceReader.device == nil {
 			// Try to get the device
 			found := deviceReader.findDevice()
 			if !found {
 				// If not found, just wait some time and try again
-				time.Sleep(5000 * time.Millisecond)
+				select {
+				case <-time.After(5000 * time.Millisecond):
+				case <-ctx.Done():
+					return
+				}
 				continue
 			}
 
 			deviceReader.logger.Info("Device connected\n")
+			deviceReader.setStatus(deviceReader.DeviceID, StatusConnected)
 		}
 
 		// Wait for an event from the device. Here we cannot wait indefenetely since
 		// if the device is unplugged, no event will be triggered and we would be stuck
 		// at the "wait" call.
-		res, _ := deviceReader.device.Wait(uint32(polling_ms))
+		res, _ := deviceReader.device.Wait(uint32(pollingInterval.Milliseconds()))
 
 		// Mostly two cases here:
 		//  1 - the "wait" call returns because an event has been signaled, probably a keystroke
@@ -115,8 +324,17 @@ func (deviceReader *DeviceReader) readCharacters(characters chan string, polling
 				// The device has probably disconnected,
 				// lose handle to the device so that next iteration will try to find it again
 				deviceReader.logger.Info("Device disconnected\n")
-				deviceReader.device.Close()
-				deviceReader.device = nil
+				deviceReader.releaseDevice()
+
+				// The partial scan of the device must not be glued to the
+				// first scan after it is plugged again
+				select {
+				case characters <- input{reset: true}:
+				case <-ctx.Done():
+					return
+				}
+
+				deviceReader.setStatus(deviceReader.DeviceID, StatusDisconnected)
 			}
 
 			// The device has not disconnected, just no event was fired during the timeout,
@@ -133,58 +351,68 @@ func (deviceReader *DeviceReader) readCharacters(characters chan string, polling
 		}
 
 		for _, keystroke := range keystrokes {
-			// Skip everything that is not a keydown event
-			if keystroke.State != interception.INTERCEPTION_KEY_DOWN {
-				continue
+			code := keystroke.Code
+			if keystroke.State&interception.INTERCEPTION_KEY_E0 != 0 {
+				extended, ok := extendedKeys[code]
+				if !ok {
+					// Arrows, home/end and such, nothing to decode
+					continue
+				}
+
+				code = extended
 			}
 
-			// Convert the keystroke code to a character using the CharMap
-			character := CharMap[(uint16)(keystroke.Code)]
+			// Key releases are needed too, to keep track of the modifiers state
+			pressed := keystroke.State&interception.INTERCEPTION_KEY_UP == 0
+
+			// Convert the keystroke code to a character using the keyboard layout
+			character := deviceReader.keyboard.HandleKey(code, pressed)
+			if character == "" {
+				continue
+			}
 
-			characters <- character
+			select {
+			case characters <- input{text: character}:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 }
 
-func (deviceReader *DeviceReader) Run(
-	ctx context.Context,
-	scans chan Scan,
-	polling_ms int16,
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
+// Build the reader of a device found by a spawner
+func (spawner *DeviceSpawner) newReader(id string, selector DeviceSelector) *DeviceReader {
+	return &DeviceReader{
+		DeviceID:    id,
+		Selector:    selector,
+		Layout:      spawner.Layout,
+		AltCodes:    spawner.AltCodes,
+		ScanOptions: spawner.ScanOptions,
+		Passthrough: spawner.Passthrough,
+	}
+}
 
+// Recordings are played back as evdev devices, which Windows readers do
+// not read
+func newReplayReader(id string, layout *Layout, altCodes string, options ScanOptions, passthrough *PassthroughOptions, events *ReplayEvents) (Source, error) {
+	return nil, errors.New("replay is only supported on Linux")
+}
+
+func (deviceReader *DeviceReader) Run(ctx context.Context, scans chan<- Scan) {
 	if deviceReader.logger == nil {
 		deviceReader.logger = logging.GetLogger("READER:" + deviceReader.DeviceID)
 	}
 
-	characters := make(chan string, 1)
+	deviceReader.keyboard.Layout = deviceReader.Layout
+	deviceReader.keyboard.AltCodes = deviceReader.AltCodes
 
-	// Start a new goroutine that reads from the device, one character at a time
-	go deviceReader.readCharacters(characters, polling_ms)
+	// Assemble the characters into scans until the context is done
+	scanAssembler := newAssembler(deviceReader.ScanOptions, deviceReader.DeviceID, deviceReader.logger)
+	scanAssembler.run(ctx, deviceReader.readCharacters, scans)
 
-	for {
-		select {
-		case <-ctx.Done():
-			deviceReader.logger.Info("Stopping device reader: %s", deviceReader.DeviceID)
-			return
-		case character := <-characters:
-			// Append the new character to the buffer
-			deviceReader.buffer += character
-
-			// Check if the buffer matches the full_scan_regex
-			if deviceReader.Regex.Match([]byte(deviceReader.buffer)) {
-				scan := Scan{
-					DeviceID:  deviceReader.DeviceID,
-					Content:   deviceReader.buffer,
-					Timestamp: time.Now().Unix(),
-				}
-
-				deviceReader.logger.Info("Read scan (%s)", strings.ReplaceAll(scan.Content, "\n", ""))
+	deviceReader.setStatus(deviceReader.DeviceID, StatusStopped)
+}
 
-				scans <- scan
-				deviceReader.buffer = ""
-			}
-		}
-	}
+func (deviceReader *DeviceReader) ID() string {
+	return deviceReader.DeviceID
 }
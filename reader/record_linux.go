@@ -0,0 +1,69 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"io"
+)
+
+// Write the raw events of the device matching the selector to w until the
+// context is done or the device is lost, returning the number of events
+// recorded. A grabbed device does not send its keystrokes to anything else
+// while recording.
+func Record(ctx context.Context, selector *DeviceSelector, grab bool, w io.Writer) (int, error) {
+	device, err := evdevEvents{}.Open(selector)
+	if err != nil {
+		return 0, err
+	}
+
+	// Closing the device unblocks the pending read
+	stop := context.AfterFunc(ctx, func() { device.Close() })
+	defer func() {
+		if stop() {
+			device.Close()
+		}
+	}()
+
+	if grab {
+		err = device.Grab()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+
+	for {
+		event, err := device.ReadEvent()
+		if err != nil {
+			if ctx.Err() != nil {
+				return count, nil
+			}
+
+			return count, err
+		}
+
+		err = WriteInputEvent(w, event)
+		if err != nil {
+			return count, err
+		}
+
+		count++
+	}
+}
@@ -0,0 +1,245 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"sync"
+	"time"
+)
+
+// Plays back a recording made with the record command
+const SourceReplay = "replay"
+
+// Read all the events of a recording, made of one JSON encoded event per line
+func ReadRecording(r io.Reader) ([]InputEvent, error) {
+	events := []InputEvent{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event InputEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, scanner.Err()
+}
+
+// Append an event to a recording
+func WriteInputEvent(w io.Writer, event InputEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(line, '\n'))
+
+	return err
+}
+
+// Plays a recording back as an input device, so that a DeviceReader decodes
+// it exactly as the device it was made from. The recording is played once,
+// the device is then disconnected for good.
+type ReplayEvents struct {
+	DeviceID string
+	Path     string
+	// Playback speed relative to the recording, e.g. 2 is twice as fast.
+	// Zero replays at the original speed.
+	Speed float64
+
+	played bool
+	logger *logging.Logger
+}
+
+func (replay *ReplayEvents) Open(selector *DeviceSelector) (EventDevice, error) {
+	if replay.played {
+		return nil, ErrDeviceNotFound
+	}
+	replay.played = true
+
+	if replay.logger == nil {
+		replay.logger = logging.GetLogger("READER:" + replay.DeviceID)
+	}
+
+	file, err := os.Open(replay.Path)
+	if err != nil {
+		replay.logger.Error("Cannot open recording: %s", err)
+		return nil, ErrDeviceNotFound
+	}
+
+	events, err := ReadRecording(file)
+	file.Close()
+
+	if err != nil {
+		replay.logger.Error("Invalid recording %s: %s", replay.Path, err)
+		return nil, ErrDeviceNotFound
+	}
+
+	replay.logger.Info("Replaying %d events from %s", len(events), replay.Path)
+
+	speed := replay.Speed
+	if speed == 0 {
+		speed = 1
+	}
+
+	return &replayDevice{
+		path:   replay.Path,
+		events: events,
+		speed:  speed,
+		closed: make(chan struct{}),
+		logger: replay.logger,
+	}, nil
+}
+
+// Nothing is ever plugged in, the recording is only played once
+func (replay *ReplayEvents) Watch(ctx context.Context) <-chan struct{} {
+	return make(chan struct{})
+}
+
+// Create a virtual keyboard able to press the keys of the recording
+func (replay *ReplayEvents) Mirror(device EventDevice) (EventSink, error) {
+	played, ok := device.(*replayDevice)
+	if !ok {
+		return nil, errors.New("not a replayed device")
+	}
+
+	return NewVirtualKeyboard(PassthroughDeviceName+" (replay)", played.keys())
+}
+
+// A recording is not a device that can be selected
+func (replay *ReplayEvents) List(selector *DeviceSelector) ([]*DeviceInfo, error) {
+	return nil, nil
+}
+
+// The events of a recording, read with their original delays
+type replayDevice struct {
+	path      string
+	events    []InputEvent
+	next      int
+	speed     float64
+	closed    chan struct{}
+	closeOnce sync.Once
+	logger    *logging.Logger
+}
+
+// Nothing else reads a recording
+func (device *replayDevice) Grab() error {
+	return nil
+}
+
+// Wait for the next event of the recording, failing once it is over
+func (device *replayDevice) ReadEvent() (InputEvent, error) {
+	if device.next >= len(device.events) {
+		device.logger.Info("Replay of %s finished", device.path)
+		return InputEvent{}, io.EOF
+	}
+
+	event := device.events[device.next]
+
+	delay := time.Duration(0)
+	if device.next > 0 {
+		delay = time.Duration(float64(event.Time-device.events[device.next-1].Time) / device.speed * float64(time.Microsecond))
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-device.closed:
+		return InputEvent{}, os.ErrClosed
+	}
+
+	device.next++
+
+	return event, nil
+}
+
+func (device *replayDevice) Path() string {
+	return device.path
+}
+
+func (device *replayDevice) Close() error {
+	device.closeOnce.Do(func() { close(device.closed) })
+	return nil
+}
+
+// The key codes pressed in the recording
+func (device *replayDevice) keys() []uint16 {
+	seen := map[uint16]bool{}
+	keys := []uint16{}
+
+	for _, event := range device.events {
+		if event.Type == EventKey && !seen[event.Code] {
+			seen[event.Code] = true
+			keys = append(keys, event.Code)
+		}
+	}
+
+	return keys
+}
+
+func newReplaySource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Path == "" {
+		return nil, errors.New("the replay type requires a path")
+	}
+
+	if config.Speed < 0 {
+		return nil, errors.New("speed cannot be negative")
+	}
+
+	layout, err := GetLayout(config.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.AltCodes {
+	case "", AltCodesCP437, AltCodesCP1252, AltCodesUnicode:
+	default:
+		return nil, fmt.Errorf("unknown alt codes '%s'", config.AltCodes)
+	}
+
+	passthrough, err := newPassthroughOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	events := &ReplayEvents{
+		DeviceID: config.ID,
+		Path:     config.Path,
+		Speed:    config.Speed,
+	}
+
+	return newReplayReader(config.ID, layout, config.AltCodes, options, passthrough, events)
+}
+
+func init() {
+	RegisterSource(SourceReplay, newReplaySource)
+}
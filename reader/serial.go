@@ -0,0 +1,271 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// A scanner in USB-COM (CDC-ACM) or RS-232 mode, read as a serial port
+const SourceSerial = "serial"
+
+// Parity of a serial port
+const (
+	ParityNone = "none"
+	ParityEven = "even"
+	ParityOdd  = "odd"
+)
+
+// Baud rates supported on every platform
+var serialBauds = []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+// Line settings of a serial port, zero values are replaced by the defaults
+// (9600 8N1)
+type serialSettings struct {
+	baud     int
+	dataBits int
+	parity   string
+	stopBits int
+}
+
+func newSerialSettings(baud int, dataBits int, parity string, stopBits int) serialSettings {
+	settings := serialSettings{baud, dataBits, parity, stopBits}
+
+	if settings.baud == 0 {
+		settings.baud = 9600
+	}
+
+	if settings.dataBits == 0 {
+		settings.dataBits = 8
+	}
+
+	if settings.parity == "" {
+		settings.parity = ParityNone
+	}
+
+	if settings.stopBits == 0 {
+		settings.stopBits = 1
+	}
+
+	return settings
+}
+
+// Check the line settings of a serial port, zero values stand for the defaults
+func ValidateSerialSettings(baud int, dataBits int, parity string, stopBits int) error {
+	settings := newSerialSettings(baud, dataBits, parity, stopBits)
+
+	if !slices.Contains(serialBauds, settings.baud) {
+		return fmt.Errorf("unsupported baud rate %d", settings.baud)
+	}
+
+	if settings.dataBits < 5 || settings.dataBits > 8 {
+		return fmt.Errorf("unsupported data bits %d", settings.dataBits)
+	}
+
+	switch settings.parity {
+	case ParityNone, ParityEven, ParityOdd:
+	default:
+		return fmt.Errorf("unknown parity '%s'", settings.parity)
+	}
+
+	if settings.stopBits != 1 && settings.stopBits != 2 {
+		return fmt.Errorf("unsupported stop bits %d", settings.stopBits)
+	}
+
+	return nil
+}
+
+// Reads scans from a scanner attached to a serial port, reopening the port
+// whenever it is lost (e.g. a USB scanner being unplugged)
+type SerialReader struct {
+	DeviceID  string
+	Port      string // e.g. /dev/ttyACM0 or COM3
+	Baud      int
+	DataBits  int
+	Parity    string // One of ParityNone (default), ParityEven or ParityOdd
+	StopBits  int
+	Reconnect time.Duration // Delay before reopening the port, one second if zero
+	ScanOptions
+
+	logger *logging.Logger
+	sourceState
+}
+
+// Decode the bytes read from a device into characters. Scanners send UTF-8
+// or a single byte charset, bytes which are not valid UTF-8 are decoded as
+// Latin-1. Returns the trailing bytes of an incomplete UTF-8 sequence.
+func decodeBytes(data []byte, characters []string) ([]string, []byte) {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+
+		if r == utf8.RuneError && size <= 1 {
+			if !utf8.FullRune(data) {
+				// The rest of the sequence has not been read yet
+				break
+			}
+
+			r, size = rune(data[0]), 1
+		}
+
+		characters = append(characters, string(r))
+		data = data[size:]
+	}
+
+	return characters, data
+}
+
+func (serialReader *SerialReader) readCharacters(ctx context.Context, characters chan input) {
+	reconnect := serialReader.Reconnect
+	if reconnect == 0 {
+		reconnect = pollingInterval
+	}
+
+	settings := newSerialSettings(serialReader.Baud, serialReader.DataBits, serialReader.Parity, serialReader.StopBits)
+
+	// Errors are only logged when they change, not at every attempt
+	lastError := ""
+
+	for {
+		port, err := openSerial(serialReader.Port, settings)
+		if err != nil {
+			if err.Error() != lastError {
+				serialReader.logger.Error("Cannot open %s: %s", serialReader.Port, err)
+			}
+			lastError = err.Error()
+		} else {
+			lastError = ""
+
+			serialReader.logger.Info("Port %s opened", serialReader.Port)
+			serialReader.setStatus(serialReader.DeviceID, StatusConnected)
+
+			err = serialReader.readPort(ctx, port, characters)
+			if ctx.Err() != nil {
+				return
+			}
+
+			serialReader.logger.Error("Lost port %s: %s", serialReader.Port, err)
+
+			// The partial scan of the port must not be glued to the first
+			// scan after it is reopened
+			select {
+			case characters <- input{reset: true}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		serialReader.setStatus(serialReader.DeviceID, StatusDisconnected)
+
+		select {
+		case <-time.After(reconnect):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Read characters from an open port until it fails or the context is done,
+// the port is closed before returning
+func (serialReader *SerialReader) readPort(ctx context.Context, port io.ReadCloser, characters chan input) error {
+	// Closing the port unblocks the pending read
+	stop := context.AfterFunc(ctx, func() { port.Close() })
+	defer func() {
+		if stop() {
+			port.Close()
+		}
+	}()
+
+	buffer := make([]byte, 256)
+	var pending []byte
+	var decoded []string
+
+	for {
+		n, err := port.Read(buffer)
+
+		if n > 0 {
+			decoded, pending = decodeBytes(append(pending, buffer[:n]...), decoded[:0])
+
+			for _, character := range decoded {
+				select {
+				case characters <- input{text: character}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		if err == io.EOF {
+			// Reading a tty returns EOF once the device is gone
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (serialReader *SerialReader) Run(ctx context.Context, scans chan<- Scan) {
+	if serialReader.logger == nil {
+		serialReader.logger = logging.GetLogger("READER:" + serialReader.DeviceID)
+	}
+
+	// Assemble the characters into scans until the context is done
+	scanAssembler := newAssembler(serialReader.ScanOptions, serialReader.DeviceID, serialReader.logger)
+	scanAssembler.run(ctx, serialReader.readCharacters, scans)
+
+	serialReader.setStatus(serialReader.DeviceID, StatusStopped)
+}
+
+func (serialReader *SerialReader) ID() string {
+	return serialReader.DeviceID
+}
+
+func newSerialSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	if config.Port == "" {
+		return nil, errors.New("the serial type requires a port")
+	}
+
+	err := ValidateSerialSettings(config.Baud, config.DataBits, config.Parity, config.StopBits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SerialReader{
+		DeviceID:    config.ID,
+		Port:        config.Port,
+		Baud:        config.Baud,
+		DataBits:    config.DataBits,
+		Parity:      config.Parity,
+		StopBits:    config.StopBits,
+		Reconnect:   time.Duration(config.ReconnectMs) * time.Millisecond,
+		ScanOptions: options,
+	}, nil
+}
+
+func init() {
+	RegisterSource(SourceSerial, newSerialSource)
+}
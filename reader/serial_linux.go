@@ -0,0 +1,98 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var serialBaudFlags = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+var serialDataBitsFlags = map[int]uint32{
+	5: unix.CS5,
+	6: unix.CS6,
+	7: unix.CS7,
+	8: unix.CS8,
+}
+
+// Open a tty in raw mode with the given line settings
+func openSerial(port string, settings serialSettings) (io.ReadCloser, error) {
+	// Opened non blocking so that reads go through the runtime poller and
+	// can be interrupted by closing the file
+	fd, err := unix.Open(port, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: port, Err: err}
+	}
+
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "tcgets", Path: port, Err: err}
+	}
+
+	baud := serialBaudFlags[settings.baud]
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.INPCK
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CBAUD | unix.CRTSCTS
+	termios.Cflag |= unix.CREAD | unix.CLOCAL | serialDataBitsFlags[settings.dataBits] | baud
+	termios.Ispeed = baud
+	termios.Ospeed = baud
+
+	switch settings.parity {
+	case ParityEven:
+		termios.Cflag |= unix.PARENB
+		termios.Iflag |= unix.INPCK
+	case ParityOdd:
+		termios.Cflag |= unix.PARENB | unix.PARODD
+		termios.Iflag |= unix.INPCK
+	}
+
+	if settings.stopBits == 2 {
+		termios.Cflag |= unix.CSTOPB
+	}
+
+	// Return as soon as a single byte is available
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+	if err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "tcsets", Path: port, Err: err}
+	}
+
+	return os.NewFile(uintptr(fd), port), nil
+}
@@ -0,0 +1,141 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// A COM port opened for reading
+type comPort struct {
+	handle windows.Handle
+	closed atomic.Bool
+}
+
+func (port *comPort) Read(buffer []byte) (int, error) {
+	for {
+		if port.closed.Load() {
+			return 0, os.ErrClosed
+		}
+
+		var n uint32
+		err := windows.ReadFile(port.handle, buffer, &n, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		// No bytes means the read timed out, not that the port is gone
+		if n > 0 {
+			return int(n), nil
+		}
+	}
+}
+
+func (port *comPort) Close() error {
+	if port.closed.Swap(true) {
+		return nil
+	}
+
+	return windows.CloseHandle(port.handle)
+}
+
+// Open a COM port with the given line settings
+func openSerial(port string, settings serialSettings) (io.ReadCloser, error) {
+	// COM10 and above are only reachable through the device namespace
+	name := port
+	if !strings.HasPrefix(name, `\\.\`) {
+		name = `\\.\` + name
+	}
+
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := windows.CreateFile(
+		path,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: port, Err: err}
+	}
+
+	var dcb windows.DCB
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+
+	err = windows.GetCommState(handle, &dcb)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, &os.PathError{Op: "getcommstate", Path: port, Err: err}
+	}
+
+	// Binary mode, DTR asserted as most USB-COM scanners expect it
+	dcb.Flags = 0x01 | windows.DTR_CONTROL_ENABLE | windows.RTS_CONTROL_ENABLE
+	dcb.BaudRate = uint32(settings.baud)
+	dcb.ByteSize = uint8(settings.dataBits)
+
+	switch settings.parity {
+	case ParityEven:
+		dcb.Parity = windows.EVENPARITY
+		dcb.Flags |= 0x02
+	case ParityOdd:
+		dcb.Parity = windows.ODDPARITY
+		dcb.Flags |= 0x02
+	default:
+		dcb.Parity = windows.NOPARITY
+	}
+
+	if settings.stopBits == 2 {
+		dcb.StopBits = windows.TWOSTOPBITS
+	} else {
+		dcb.StopBits = windows.ONESTOPBIT
+	}
+
+	err = windows.SetCommState(handle, &dcb)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, &os.PathError{Op: "setcommstate", Path: port, Err: err}
+	}
+
+	// Return whatever is available, waiting at most 100 ms for the first
+	// byte so that closing the port is noticed
+	timeouts := windows.CommTimeouts{
+		ReadIntervalTimeout:        0xFFFFFFFF,
+		ReadTotalTimeoutMultiplier: 0xFFFFFFFF,
+		ReadTotalTimeoutConstant:   100,
+	}
+
+	err = windows.SetCommTimeouts(handle, &timeouts)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, &os.PathError{Op: "setcommtimeouts", Path: port, Err: err}
+	}
+
+	return &comPort{handle: handle}, nil
+}
@@ -0,0 +1,336 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sirafino/go-barcode-relay/configuration"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// How often the sources look for their device when it is not connected,
+// unless notified of changes
+const pollingInterval = 1000 * time.Millisecond
+
+// A keyboard wedge scanner, read as an input device (the default source)
+const SourceEvdev = "evdev"
+
+type SourceStatus string
+
+const (
+	StatusStarting     SourceStatus = "starting"
+	StatusConnected    SourceStatus = "connected"
+	StatusDisconnected SourceStatus = "disconnected"
+	StatusStopped      SourceStatus = "stopped"
+)
+
+// Called whenever the status of a source changes
+type StatusHook func(id string, status SourceStatus)
+
+// Anything producing scans: a device reader, a spawner of readers, a serial
+// port...
+type Source interface {
+	// The id of the device, sent along with its scans
+	ID() string
+	// Produce scans until the context is done
+	Run(ctx context.Context, scans chan<- Scan)
+	// The current status, safe to call while the source is running
+	Status() SourceStatus
+	// Register a function to be called on every status change
+	OnStatusChange(hook StatusHook)
+}
+
+// Status tracking shared by all the sources
+type sourceState struct {
+	mutex  sync.Mutex
+	status SourceStatus
+	hooks  []StatusHook
+}
+
+func (state *sourceState) Status() SourceStatus {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.status == "" {
+		return StatusStarting
+	}
+
+	return state.status
+}
+
+func (state *sourceState) OnStatusChange(hook StatusHook) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	state.hooks = append(state.hooks, hook)
+}
+
+func (state *sourceState) setStatus(id string, status SourceStatus) {
+	state.mutex.Lock()
+	if state.status == status {
+		state.mutex.Unlock()
+		return
+	}
+
+	state.status = status
+	hooks := state.hooks
+	state.mutex.Unlock()
+
+	// Hooks are called without holding the lock, so they can query the source
+	for _, hook := range hooks {
+		hook(id, status)
+	}
+}
+
+// Build a source from its device configuration and the scan options shared
+// by all the sources
+type SourceFactory func(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error)
+
+var sources = map[string]SourceFactory{}
+
+// Make a source available to devices through the "type" configuration option
+func RegisterSource(name string, factory SourceFactory) {
+	sources[name] = factory
+}
+
+// Names of all the registered sources, sorted
+func SourceTypes() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Build the source of a device, based on its type
+func NewSource(config *configuration.DeviceConfiguration) (Source, error) {
+	sourceType := config.SourceType()
+	if sourceType == "" {
+		sourceType = SourceEvdev
+	}
+
+	factory, ok := sources[sourceType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type '%s' (available: %s)", sourceType, strings.Join(SourceTypes(), ", "))
+	}
+
+	if config.MatchAll && sourceType != SourceEvdev {
+		return nil, fmt.Errorf("match_all is only supported by the %s type", SourceEvdev)
+	}
+
+	// The timing of the other sources says nothing about who typed a scan
+	if config.Timing != nil && sourceType != SourceEvdev && sourceType != SourceReplay {
+		return nil, fmt.Errorf("timing is only supported by the %s and %s types", SourceEvdev, SourceReplay)
+	}
+
+	if config.Timing != nil && config.Passthrough {
+		return nil, fmt.Errorf("timing cannot be used with passthrough, which already tells typing from scans")
+	}
+
+	options, err := NewScanOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory(config, options)
+}
+
+// Build the scan options of a device, checking their values
+func NewScanOptions(config *configuration.DeviceConfiguration) (ScanOptions, error) {
+	regex, err := regexp.Compile(config.FullScanRegex)
+	if err != nil {
+		return ScanOptions{}, fmt.Errorf("invalid full_scan_regex: %w", err)
+	}
+
+	switch config.IdleAction {
+	case "", IdleDiscard, IdleFlush:
+	default:
+		return ScanOptions{}, fmt.Errorf("unknown idle action '%s'", config.IdleAction)
+	}
+
+	switch config.Framing {
+	case "", FramingRegex:
+	case FramingDelimited:
+		if config.Suffix == "" {
+			return ScanOptions{}, fmt.Errorf("delimited framing requires a suffix")
+		}
+	default:
+		return ScanOptions{}, fmt.Errorf("unknown framing '%s'", config.Framing)
+	}
+
+	switch config.Symbology {
+	case "", SymbologyDetect, SymbologyAIM, SymbologyHoneywell:
+	default:
+		return ScanOptions{}, fmt.Errorf("unknown symbology mode '%s'", config.Symbology)
+	}
+
+	var validator *Validator
+	if config.Validation != nil {
+		validator, err = NewValidator(
+			config.Validation.Checks,
+			config.Validation.Lengths,
+			config.Validation.Action,
+		)
+		if err != nil {
+			return ScanOptions{}, err
+		}
+	}
+
+	timing, err := newTimingOptions(config.Timing)
+	if err != nil {
+		return ScanOptions{}, err
+	}
+
+	return ScanOptions{
+		Regex:       regex,
+		Framing:     config.Framing,
+		Prefix:      config.Prefix,
+		Suffix:      config.Suffix,
+		IdleTimeout: time.Duration(config.IdleTimeoutMs) * time.Millisecond,
+		FlushOnIdle: config.IdleAction == IdleFlush,
+		MaxLength:   config.MaxLength,
+		Symbology:   config.Symbology,
+		GS1:         config.GS1,
+		Validator:   validator,
+		Timing:      timing,
+	}, nil
+}
+
+// Build the selector of a device, which must not be empty
+func NewDeviceSelector(config *configuration.DeviceConfiguration) (DeviceSelector, error) {
+	selector := DeviceSelector{
+		VID:  config.VID,
+		PID:  config.PID,
+		Name: config.Name,
+		Phys: config.Phys,
+		Uniq: config.Uniq,
+	}
+
+	if config.HWIDRegex != "" {
+		var err error
+		selector.HWIDRegex, err = regexp.Compile(config.HWIDRegex)
+		if err != nil {
+			return DeviceSelector{}, fmt.Errorf("invalid hwid_regex: %w", err)
+		}
+	}
+
+	if selector.IsEmpty() {
+		return DeviceSelector{}, fmt.Errorf("at least one of vid, pid, name, phys, uniq or hwid_regex is required")
+	}
+
+	return selector, nil
+}
+
+// Keyboard wedge scanners, read as input devices
+func newKeyboardSource(config *configuration.DeviceConfiguration, options ScanOptions) (Source, error) {
+	selector, err := NewDeviceSelector(config)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := GetLayout(config.Layout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.AltCodes {
+	case "", AltCodesCP437, AltCodesCP1252, AltCodesUnicode:
+	default:
+		return nil, fmt.Errorf("unknown alt codes '%s'", config.AltCodes)
+	}
+
+	passthrough, err := newPassthroughOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.MatchAll {
+		return &DeviceReader{
+			DeviceID:    config.ID,
+			Selector:    selector,
+			Layout:      layout,
+			AltCodes:    config.AltCodes,
+			ScanOptions: options,
+			Passthrough: passthrough,
+		}, nil
+	}
+
+	// Spawn a reader for each matching device
+	idTemplate := config.IDTemplate
+	if idTemplate == "" {
+		idTemplate = DefaultIDTemplate
+	}
+
+	tmpl, err := template.New(config.ID).Option("missingkey=error").Parse(idTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_template: %w", err)
+	}
+
+	return &DeviceSpawner{
+		DeviceID:    config.ID,
+		Selector:    selector,
+		Layout:      layout,
+		AltCodes:    config.AltCodes,
+		ScanOptions: options,
+		Passthrough: passthrough,
+		IDTemplate:  tmpl,
+	}, nil
+}
+
+// Build the passthrough settings of a device, nil if disabled
+func newPassthroughOptions(config *configuration.DeviceConfiguration) (*PassthroughOptions, error) {
+	if !config.Passthrough {
+		return nil, nil
+	}
+
+	if !VirtualKeyboardSupported {
+		return nil, fmt.Errorf("passthrough is only supported on Linux")
+	}
+
+	if config.PassthroughGapMs < 0 || config.PassthroughMinLength < 0 {
+		return nil, fmt.Errorf("passthrough_gap_ms and passthrough_min_length cannot be negative")
+	}
+
+	options := &PassthroughOptions{
+		MaxGap:    DefaultPassthroughGap,
+		MinLength: DefaultPassthroughMinLength,
+	}
+
+	if config.PassthroughGapMs > 0 {
+		options.MaxGap = time.Duration(config.PassthroughGapMs) * time.Millisecond
+	}
+
+	if config.PassthroughMinLength > 0 {
+		options.MinLength = config.PassthroughMinLength
+	}
+
+	return options, nil
+}
+
+func init() {
+	RegisterSource(SourceEvdev, newKeyboardSource)
+}
@@ -0,0 +1,216 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"context"
+	"fmt"
+	"sirafino/go-barcode-relay/logging"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// The default template for the ids of spawned readers, the device path is
+// used when the physical location is not known
+const DefaultIDTemplate = "{{.id}}-{{or .phys .path}}"
+
+// Spawns a DeviceReader for each device matching a wildcard selector, adding
+// and removing readers as devices are connected and disconnected.
+type DeviceSpawner struct {
+	// The configuration shared by all the spawned readers, DeviceID and
+	// Selector are used to derive the ones of each reader
+	DeviceID string
+	Selector DeviceSelector
+	Layout   *Layout
+	AltCodes string
+	ScanOptions
+	Passthrough *PassthroughOptions
+
+	// The template for the ids of spawned readers, executed with the id, path,
+	// name, phys, uniq, vid, pid and hwid of each device
+	IDTemplate *template.Template
+
+	// Where devices are listed and opened from, the system devices if nil.
+	// Tests replace it to feed scripted devices (Linux only).
+	Events EventSource
+
+	sourceState
+
+	// The running readers and their ids, by device key
+	readers map[string]context.CancelFunc
+	ids     map[string]string
+	// The devices whose reader id is already used, only reported once
+	rejected map[string]bool
+	wg       sync.WaitGroup
+	logger   *logging.Logger
+}
+
+func (spawner *DeviceSpawner) ID() string {
+	return spawner.DeviceID
+}
+
+// The key identifying a device. The nodes of a device share its physical
+// location and serial number, so the path is needed to tell them apart.
+func deviceKey(info *DeviceInfo) string {
+	return info.Path + "|" + info.Phys
+}
+
+// Build the id and the selector of the reader for a device
+func (spawner *DeviceSpawner) readerFor(info *DeviceInfo) (string, DeviceSelector, error) {
+	var id strings.Builder
+	err := spawner.IDTemplate.Execute(&id, map[string]string{
+		"id":   spawner.DeviceID,
+		"path": info.Path,
+		"name": info.Name,
+		"phys": info.Phys,
+		"uniq": info.Uniq,
+		"vid":  fmt.Sprintf("%04X", info.VID),
+		"pid":  fmt.Sprintf("%04X", info.PID),
+		"hwid": info.HWID,
+	})
+	if err != nil {
+		return "", DeviceSelector{}, err
+	}
+
+	// Narrow the selector down to this device only
+	selector := spawner.Selector
+	selector.Path = info.Path
+	selector.Phys = info.Phys
+
+	return id.String(), selector, nil
+}
+
+// Look for matching devices, spawning readers for the new ones and
+// stopping the readers of the ones that are gone
+func (spawner *DeviceSpawner) update(ctx context.Context, scans chan<- Scan) {
+	var infos []*DeviceInfo
+	var err error
+	if spawner.Events != nil {
+		infos, err = spawner.Events.List(&spawner.Selector)
+	} else {
+		infos, err = ListMatchingDevices(&spawner.Selector)
+	}
+	if err != nil {
+		spawner.logger.Error("Unable to list devices: %s", err)
+		return
+	}
+
+	present := map[string]bool{}
+
+	for _, info := range infos {
+		key := deviceKey(info)
+		present[key] = true
+
+		if _, ok := spawner.readers[key]; ok {
+			continue
+		}
+
+		id, selector, err := spawner.readerFor(info)
+		if err != nil {
+			spawner.logger.Error("Unable to build the reader id for %s: %s", info.Path, err)
+			continue
+		}
+
+		if spawner.idInUse(id) {
+			if !spawner.rejected[key] {
+				spawner.logger.Error("Reader id %s for %s is already used, the id_template must tell the devices apart", id, info.Path)
+				spawner.rejected[key] = true
+			}
+			continue
+		}
+
+		deviceReader := spawner.newReader(id, selector)
+
+		readerCtx, cancel := context.WithCancel(ctx)
+		spawner.readers[key] = cancel
+		spawner.ids[key] = id
+
+		spawner.logger.Info("Spawning reader %s for %s", id, info.Path)
+
+		spawner.wg.Add(1)
+		go func() {
+			defer spawner.wg.Done()
+			deviceReader.Run(readerCtx, scans)
+		}()
+	}
+
+	for key, cancel := range spawner.readers {
+		if !present[key] {
+			spawner.logger.Info("Device %s is gone, stopping its reader", key)
+			cancel()
+			delete(spawner.readers, key)
+			delete(spawner.ids, key)
+		}
+	}
+
+	for key := range spawner.rejected {
+		if !present[key] {
+			delete(spawner.rejected, key)
+		}
+	}
+
+	if len(spawner.readers) > 0 {
+		spawner.setStatus(spawner.DeviceID, StatusConnected)
+	} else {
+		spawner.setStatus(spawner.DeviceID, StatusDisconnected)
+	}
+}
+
+// Whether a running reader already has the id
+func (spawner *DeviceSpawner) idInUse(id string) bool {
+	for _, other := range spawner.ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (spawner *DeviceSpawner) Run(ctx context.Context, scans chan<- Scan) {
+	if spawner.logger == nil {
+		spawner.logger = logging.GetLogger("SPAWNER:" + spawner.DeviceID)
+	}
+
+	spawner.readers = map[string]context.CancelFunc{}
+	spawner.ids = map[string]string{}
+	spawner.rejected = map[string]bool{}
+
+	// Signaled when devices are plugged or unplugged
+	var changes <-chan struct{}
+	if spawner.Events != nil {
+		changes = spawner.Events.Watch(ctx)
+	} else {
+		changes = watchDevices(ctx)
+	}
+
+	for {
+		spawner.update(ctx, scans)
+
+		select {
+		case <-ctx.Done():
+			// Spawned readers are stopped by the same context
+			spawner.logger.Info("Stopping device spawner: %s", spawner.DeviceID)
+			spawner.wg.Wait()
+			spawner.setStatus(spawner.DeviceID, StatusStopped)
+			return
+		case <-changes:
+		}
+	}
+}
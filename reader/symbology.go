@@ -0,0 +1,172 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"strings"
+)
+
+// How the symbology of a scan is detected
+const (
+	// Only guess the symbology from the content
+	SymbologyDetect = "detect"
+	// Parse and strip AIM symbology identifiers (e.g. ]C1), guessing the
+	// symbology from the content when missing
+	SymbologyAIM = "aim"
+	// Parse and strip Honeywell Code IDs (a single character prefix),
+	// guessing the symbology from the content when missing
+	SymbologyHoneywell = "honeywell"
+)
+
+// AIM symbology identifiers, by code character. Identifiers whose meaning
+// depends on the modifier are listed with it.
+var aimSymbologies = map[string]string{
+	"A":  "Code 39",
+	"B":  "Telepen",
+	"C":  "Code 128",
+	"C1": "GS1-128",
+	"D":  "Code One",
+	"E":  "EAN/UPC",
+	"E0": "EAN-13",
+	"E4": "EAN-8",
+	"F":  "Codabar",
+	"G":  "Code 93",
+	"H":  "Code 11",
+	"I":  "ITF",
+	"L":  "PDF417",
+	"M":  "MSI",
+	"Q":  "QR Code",
+	"Q3": "GS1 QR Code",
+	"R":  "Straight 2 of 5",
+	"S":  "Industrial 2 of 5",
+	"X":  "Other",
+	"d":  "Data Matrix",
+	"d2": "GS1 DataMatrix",
+	"e":  "GS1 DataBar",
+	"z":  "Aztec",
+}
+
+// Honeywell Code IDs
+var honeywellSymbologies = map[byte]string{
+	'a': "Codabar",
+	'b': "Code 39",
+	'c': "UPC-A",
+	'd': "EAN-13",
+	'D': "EAN-8",
+	'E': "UPC-E",
+	'e': "ITF",
+	'i': "Code 93",
+	'I': "GS1-128",
+	'j': "Code 128",
+	'r': "PDF417",
+	's': "QR Code",
+	'w': "Data Matrix",
+	'y': "GS1 DataBar",
+	'z': "Aztec",
+}
+
+// Parse and strip an AIM symbology identifier (]cm) from the content
+func parseAIM(content string) (string, string, bool) {
+	if len(content) < 3 || content[0] != ']' {
+		return "", content, false
+	}
+
+	code := content[1:2]
+	modifier := content[2:3]
+
+	if symbology, ok := aimSymbologies[code+modifier]; ok {
+		return symbology, content[3:], true
+	}
+
+	if symbology, ok := aimSymbologies[code]; ok {
+		return symbology, content[3:], true
+	}
+
+	return "", content, false
+}
+
+// Parse and strip a Honeywell Code ID from the content
+func parseHoneywell(content string) (string, string, bool) {
+	if len(content) < 2 {
+		return "", content, false
+	}
+
+	if symbology, ok := honeywellSymbologies[content[0]]; ok {
+		return symbology, content[1:], true
+	}
+
+	return "", content, false
+}
+
+// Guess the symbology of a code from its content, empty when the content
+// does not tell
+func guessSymbology(content string) string {
+	code := strings.TrimRight(content, "\r\n")
+
+	if code == "" {
+		return ""
+	}
+
+	if isDigits(code) {
+		switch {
+		case len(code) == 13 && checkMod10(code):
+			return "EAN-13"
+		case len(code) == 12 && checkMod10(code):
+			return "UPC-A"
+		case len(code) == 8 && checkMod10(code):
+			return "EAN-8"
+		case len(code) == 8 && checkUPCE(code):
+			return "UPC-E"
+		case len(code) == 14 && checkMod10(code):
+			return "ITF-14"
+		}
+	}
+
+	if strings.HasPrefix(code, "http://") || strings.HasPrefix(code, "https://") {
+		return "QR Code"
+	}
+
+	if strings.Contains(code, GS) || strings.HasPrefix(code, "(") {
+		if _, err := ParseGS1(code); err == nil {
+			return "GS1"
+		}
+	}
+
+	// Anything else could be one of many symbologies
+	return ""
+}
+
+// Detect the symbology of a scan, returning it along with the content
+// stripped of the symbology identifier
+func DetectSymbology(content string, mode string) (string, string) {
+	var symbology string
+	var found bool
+
+	switch mode {
+	case SymbologyAIM:
+		symbology, content, found = parseAIM(content)
+	case SymbologyHoneywell:
+		symbology, content, found = parseHoneywell(content)
+	}
+
+	if found {
+		return symbology, content
+	}
+
+	return guessSymbology(content), content
+}
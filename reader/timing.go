@@ -0,0 +1,248 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"sirafino/go-barcode-relay/configuration"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Why the keystrokes of a scan do not look like they come from a scanner
+const (
+	// Typed as slowly as a person would
+	TimingHuman = "human"
+	// Typed faster than the minimum interval
+	TimingTooFast = "too_fast"
+	// Typed much faster or slower than the device usually does
+	TimingAnomalous = "anomalous"
+)
+
+// Defaults of the timing analysis settings
+const (
+	DefaultHumanInterval = 50 * time.Millisecond
+	DefaultLearnScans    = 10
+	DefaultTolerance     = 3.0
+)
+
+// Scans with fewer characters are too short to tell anything from their
+// timing
+const minTimedCharacters = 4
+
+// How the keystrokes of a device are told apart from those of a person or of
+// a keyboard injection device. Scanners type in fast and regular bursts: the
+// median interval between the characters of a scan is compared against
+// fixed thresholds and against the profile of the device, learned from its
+// first scans.
+type TimingOptions struct {
+	HumanInterval time.Duration // A median interval above is human typing
+	MinInterval   time.Duration // A median interval below is too fast, zero disables it
+	LearnScans    int           // Scans profiling the device, zero disables profiling
+	Tolerance     float64       // How many times faster or slower than its profile a device may type
+	// What to do with the suspicious scans, one of InvalidDrop, InvalidFlag
+	// or InvalidRoute
+	Action string
+}
+
+// Build the timing analysis settings of a device, nil if disabled
+func newTimingOptions(config *configuration.TimingConfiguration) (*TimingOptions, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if config.HumanIntervalMs < 0 || config.MinIntervalMs < 0 || config.LearnScans < 0 || config.Tolerance < 0 {
+		return nil, fmt.Errorf("timing settings cannot be negative")
+	}
+
+	action := config.Action
+	switch action {
+	case "":
+		action = InvalidDrop
+	case InvalidDrop, InvalidFlag, InvalidRoute:
+	default:
+		return nil, fmt.Errorf("unknown suspicious scan action '%s'", action)
+	}
+
+	options := &TimingOptions{
+		HumanInterval: DefaultHumanInterval,
+		MinInterval:   time.Duration(config.MinIntervalMs) * time.Millisecond,
+		LearnScans:    DefaultLearnScans,
+		Tolerance:     DefaultTolerance,
+		Action:        action,
+	}
+
+	if config.HumanIntervalMs > 0 {
+		options.HumanInterval = time.Duration(config.HumanIntervalMs) * time.Millisecond
+	}
+	if config.LearnScans > 0 {
+		options.LearnScans = config.LearnScans
+	}
+	if config.Tolerance > 0 {
+		options.Tolerance = config.Tolerance
+	}
+
+	if options.Tolerance <= 1 {
+		return nil, fmt.Errorf("timing tolerance must be greater than 1")
+	}
+	if options.MinInterval >= options.HumanInterval {
+		return nil, fmt.Errorf("timing min_interval_ms must be lower than human_interval_ms")
+	}
+
+	return options, nil
+}
+
+// The typing profile of a device, learned from its scans
+type TimingProfile struct {
+	options TimingOptions
+
+	// The average median interval of the scans learned so far
+	learned  int
+	interval time.Duration
+}
+
+func NewTimingProfile(options TimingOptions) *TimingProfile {
+	return &TimingProfile{options: options}
+}
+
+// The learned median interval of the device, zero while still learning
+func (profile *TimingProfile) Interval() time.Duration {
+	if profile.options.LearnScans == 0 || profile.learned < profile.options.LearnScans {
+		return 0
+	}
+
+	return profile.interval
+}
+
+// Check the intervals between the characters of a scan, returning why they
+// are suspicious or an empty string. The scans which are not suspicious
+// are learned until the profile is complete.
+func (profile *TimingProfile) Analyze(intervals []time.Duration) string {
+	if len(intervals)+1 < minTimedCharacters {
+		return ""
+	}
+
+	median := medianInterval(intervals)
+
+	switch {
+	case median > profile.options.HumanInterval:
+		return TimingHuman
+	case median < profile.options.MinInterval:
+		return TimingTooFast
+	}
+
+	if learned := profile.Interval(); learned > 0 {
+		tolerance := profile.options.Tolerance
+		if float64(median) > float64(learned)*tolerance || float64(median) < float64(learned)/tolerance {
+			return TimingAnomalous
+		}
+
+		return ""
+	}
+
+	if profile.learned < profile.options.LearnScans {
+		profile.interval = (profile.interval*time.Duration(profile.learned) + median) / time.Duration(profile.learned+1)
+		profile.learned++
+	}
+
+	return ""
+}
+
+func medianInterval(intervals []time.Duration) time.Duration {
+	sorted := slices.Clone(intervals)
+	slices.Sort(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+// Raised when the keystrokes of a device do not look like a scanner, e.g. a
+// keyboard or an injection device spoofing the ids of a scanner
+type SecurityEvent struct {
+	DeviceID  string
+	Kind      string // TimingHuman, TimingTooFast or TimingAnomalous
+	Content   string
+	Intervals []time.Duration // Between the characters of the scan
+	Profile   time.Duration   // The learned median interval, zero while learning
+	Timestamp int64
+}
+
+// The event as a scan, so that it can be sent by the senders. The pattern
+// is sent in the "intervals_ms" field.
+func (event SecurityEvent) Scan() Scan {
+	scan := Scan{
+		DeviceID:  event.DeviceID,
+		Content:   event.Content,
+		Timestamp: event.Timestamp,
+	}
+
+	scan.SetField("event", "timing_"+event.Kind)
+	scan.SetField("intervals_ms", formatIntervals(event.Intervals))
+	if event.Profile > 0 {
+		scan.SetField("profile_ms", formatMs(event.Profile))
+	}
+
+	return scan
+}
+
+// Comma separated intervals in milliseconds, e.g. "120.5,98,143.2"
+func formatIntervals(intervals []time.Duration) string {
+	formatted := make([]string, len(intervals))
+	for i, interval := range intervals {
+		formatted[i] = formatMs(interval)
+	}
+
+	return strings.Join(formatted, ",")
+}
+
+func formatMs(interval time.Duration) string {
+	return strconv.FormatFloat(float64(interval.Microseconds())/1000, 'f', -1, 64)
+}
+
+// Called for every security event raised by any source
+type SecurityHook func(event SecurityEvent)
+
+var securityHooks struct {
+	mutex sync.Mutex
+	hooks []SecurityHook
+}
+
+// Register a function to be called on every security event
+func OnSecurityEvent(hook SecurityHook) {
+	securityHooks.mutex.Lock()
+	defer securityHooks.mutex.Unlock()
+
+	securityHooks.hooks = append(securityHooks.hooks, hook)
+}
+
+func raiseSecurityEvent(event SecurityEvent) {
+	securityHooks.mutex.Lock()
+	hooks := securityHooks.hooks
+	securityHooks.mutex.Unlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
@@ -21,4 +21,23 @@ type Scan struct {
 	DeviceID  string
 	Content   string
 	Timestamp int64
+
+	// The symbology of the code (e.g. EAN-13), empty if not detected
+	Symbology string
+
+	// Additional data extracted from the content (e.g. GS1 elements), sent
+	// by the senders along with the scan
+	Fields map[string]string
+
+	// Set for scans which failed validation and must be sent to the invalid
+	// scans target
+	Invalid bool
+}
+
+func (scan *Scan) SetField(name string, value string) {
+	if scan.Fields == nil {
+		scan.Fields = map[string]string{}
+	}
+
+	scan.Fields[name] = value
 }
@@ -0,0 +1,151 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A key press typing a character, with the modifiers held down around it
+type Keystroke struct {
+	Code  uint16
+	Shift bool
+	AltGr bool
+	Ctrl  bool
+}
+
+// The keystrokes typing a text with the layout, the reverse of a Keyboard.
+//
+// Characters missing from the layout are typed through its dead keys when
+// possible, control characters with ctrl (e.g. ctrl+] for GS).
+func (layout *Layout) Keystrokes(text string) ([]Keystroke, error) {
+	direct := layout.directKeystrokes()
+
+	var keystrokes []Keystroke
+	for _, character := range text {
+		typed, ok := layout.keystrokesFor(string(character), direct)
+		if !ok {
+			return nil, fmt.Errorf("cannot type %q with the %s layout", character, layout.Name)
+		}
+
+		keystrokes = append(keystrokes, typed...)
+	}
+
+	return keystrokes, nil
+}
+
+// The keystrokes typing a single character
+func (layout *Layout) keystrokesFor(character string, direct map[string]Keystroke) ([]Keystroke, bool) {
+	if isDeadKey(character) {
+		// A combining mark on its own would be composed with what follows
+		return nil, false
+	}
+
+	if keystroke, ok := direct[character]; ok {
+		return []Keystroke{keystroke}, true
+	}
+
+	if c := character[0]; len(character) == 1 && c > 0 && c < ' ' {
+		// Control character, typed with ctrl and the lowercase letter or
+		// the symbol, which must not need AltGr as it disables ctrl
+		typed := c + '@'
+		if typed >= 'A' && typed <= 'Z' {
+			typed += 'a' - 'A'
+		}
+
+		keystroke, ok := direct[string(rune(typed))]
+		if !ok || keystroke.AltGr {
+			return nil, false
+		}
+
+		keystroke.Ctrl = true
+		return []Keystroke{keystroke}, true
+	}
+
+	// Sorted, so that the same keystrokes are picked every time
+	deads := make([]string, 0, len(deadKeys))
+	for dead := range deadKeys {
+		deads = append(deads, dead)
+	}
+	sort.Strings(deads)
+
+	for _, dead := range deads {
+		deadKeystroke, ok := direct[dead]
+		if !ok {
+			continue
+		}
+
+		key := deadKeys[dead]
+		if character == key.spacing {
+			return []Keystroke{deadKeystroke, direct[" "]}, true
+		}
+
+		for i, composed := range key.composed {
+			base, ok := direct[string(key.bases[i])]
+			if string(composed) == character && ok {
+				return []Keystroke{deadKeystroke, base}, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// The keystroke typing each character produced by a single key press,
+// including the dead keys. The main keys are preferred over the numpad ones
+// and the fewer modifiers the better.
+func (layout *Layout) directKeystrokes() map[string]Keystroke {
+	codes := make([]int, 0, len(layout.Keys))
+	for code := range layout.Keys {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+
+	direct := map[string]Keystroke{}
+	add := func(character string, keystroke Keystroke) {
+		if _, ok := direct[character]; character != "" && !ok {
+			direct[character] = keystroke
+		}
+	}
+
+	// One level at a time, in order of preference
+	for level := 0; level < 4; level++ {
+		if level >= 2 && !layout.AltGr {
+			break
+		}
+
+		for _, code := range codes {
+			chars := layout.Keys[uint16(code)]
+			keystroke := Keystroke{Code: uint16(code), Shift: level%2 == 1, AltGr: level >= 2}
+
+			switch level {
+			case 0:
+				add(chars.Normal, keystroke)
+			case 1:
+				add(chars.Shifted, keystroke)
+			case 2:
+				add(chars.AltGr, keystroke)
+			case 3:
+				add(chars.ShiftedAltGr, keystroke)
+			}
+		}
+	}
+
+	return direct
+}
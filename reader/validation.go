@@ -0,0 +1,218 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package reader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// What to do with scans failing validation
+const (
+	// Do not send the scan at all
+	InvalidDrop = "drop"
+	// Send the scan with an additional valid=false field
+	InvalidFlag = "flag"
+	// Send the scan to the invalid scans target instead of the main one
+	InvalidRoute = "route"
+)
+
+// Validates scans against check digit algorithms and allowed lengths, to
+// reject truncated and misread codes.
+type Validator struct {
+	// A scan is valid if it passes at least one of the checks (a device can
+	// read many symbologies), no checks means any content is accepted
+	Checks []string
+	// Allowed content lengths, empty means any length
+	Lengths []int
+	// One of InvalidDrop, InvalidFlag or InvalidRoute
+	Action string
+}
+
+var checks = map[string]func(string) bool{
+	"ean8":   func(code string) bool { return len(code) == 8 && checkMod10(code) },
+	"ean13":  func(code string) bool { return len(code) == 13 && checkMod10(code) },
+	"upca":   func(code string) bool { return len(code) == 12 && checkMod10(code) },
+	"upce":   checkUPCE,
+	"itf14":  func(code string) bool { return len(code) == 14 && checkMod10(code) },
+	"gtin":   checkGTIN,
+	"isbn":   checkISBN,
+	"code39": checkCode39,
+}
+
+// Names of the available checks
+func CheckNames() []string {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func NewValidator(checkNames []string, lengths []int, action string) (*Validator, error) {
+	for _, name := range checkNames {
+		if _, ok := checks[name]; !ok {
+			return nil, fmt.Errorf("unknown check '%s' (available: %s)", name, strings.Join(CheckNames(), ", "))
+		}
+	}
+
+	switch action {
+	case "":
+		action = InvalidDrop
+	case InvalidDrop, InvalidFlag, InvalidRoute:
+	default:
+		return nil, fmt.Errorf("unknown invalid scan action '%s'", action)
+	}
+
+	return &Validator{
+		Checks:  checkNames,
+		Lengths: lengths,
+		Action:  action,
+	}, nil
+}
+
+// Check whether a scan is valid, the line terminator is not considered
+// part of the code
+func (validator *Validator) Validate(scan *Scan) bool {
+	code := strings.TrimRight(scan.Content, "\r\n")
+
+	if len(validator.Lengths) > 0 && !slices.Contains(validator.Lengths, len([]rune(code))) {
+		return false
+	}
+
+	if len(validator.Checks) == 0 {
+		return true
+	}
+
+	for _, name := range validator.Checks {
+		if checks[name](code) {
+			return true
+		}
+
+		// GS1 codes carry the GTIN as AI (01)
+		if gtin, ok := scan.Fields["gs1_01"]; ok && name == "gtin" && checkGTIN(gtin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GS1 mod 10 check digit, used by EAN, UPC, ITF-14, GTIN and ISBN-13. The
+// last digit is the check digit.
+func checkMod10(code string) bool {
+	if len(code) < 2 || !isDigits(code) {
+		return false
+	}
+
+	sum := 0
+	weight := 3
+	for i := len(code) - 2; i >= 0; i-- {
+		sum += int(code[i]-'0') * weight
+		weight = 4 - weight
+	}
+
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
+
+func checkGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+		return checkMod10(code)
+	}
+
+	return false
+}
+
+// UPC-E codes are validated by expanding them to UPC-A
+func checkUPCE(code string) bool {
+	if len(code) != 8 || !isDigits(code) || (code[0] != '0' && code[0] != '1') {
+		return false
+	}
+
+	d := code[1:7]
+
+	var body string
+	switch d[5] {
+	case '0', '1', '2':
+		body = d[0:2] + d[5:6] + "0000" + d[2:5]
+	case '3':
+		body = d[0:3] + "00000" + d[3:5]
+	case '4':
+		body = d[0:4] + "00000" + d[4:5]
+	default:
+		body = d[0:5] + "0000" + d[5:6]
+	}
+
+	return checkMod10(code[0:1] + body + code[7:8])
+}
+
+// ISBN-13 (mod 10) or ISBN-10 (mod 11, X standing for 10)
+func checkISBN(code string) bool {
+	code = strings.ReplaceAll(code, "-", "")
+
+	if len(code) == 13 {
+		return (strings.HasPrefix(code, "978") || strings.HasPrefix(code, "979")) && checkMod10(code)
+	}
+
+	if len(code) != 10 || !isDigits(code[:9]) {
+		return false
+	}
+
+	sum := 0
+	for i := range 9 {
+		sum += int(code[i]-'0') * (10 - i)
+	}
+
+	switch {
+	case code[9] == 'X' || code[9] == 'x':
+		sum += 10
+	case code[9] >= '0' && code[9] <= '9':
+		sum += int(code[9] - '0')
+	default:
+		return false
+	}
+
+	return sum%11 == 0
+}
+
+const code39Charset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-. $/+%"
+
+// Code 39 mod 43 check character, the start/stop '*' are ignored if present
+func checkCode39(code string) bool {
+	code = strings.TrimSuffix(strings.TrimPrefix(code, "*"), "*")
+	if len(code) < 2 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(code)-1; i++ {
+		value := strings.IndexByte(code39Charset, code[i])
+		if value < 0 {
+			return false
+		}
+
+		sum += value
+	}
+
+	return code39Charset[sum%43] == code[len(code)-1]
+}
@@ -0,0 +1,104 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"sirafino/go-barcode-relay/logging"
+	"sirafino/go-barcode-relay/reader"
+	"strconv"
+)
+
+// Parse a USB id flag, either decimal or hexadecimal (0x...)
+func usbIDFlag(value *uint16) func(string) error {
+	return func(s string) error {
+		id, err := strconv.ParseUint(s, 0, 16)
+		if err != nil {
+			return err
+		}
+
+		*value = uint16(id)
+		return nil
+	}
+}
+
+// The record command: write the raw events of a device to a file, to be
+// played back with the replay source
+func record(args []string) {
+	logger := logging.GetLogger("RECORD")
+
+	var selector reader.DeviceSelector
+
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	output := flags.String("o", "", "file to write the recording to (required)")
+	flags.StringVar(&selector.Path, "path", "", "device node, e.g. /dev/input/event3")
+	flags.Func("vid", "USB vendor id", usbIDFlag(&selector.VID))
+	flags.Func("pid", "USB product id", usbIDFlag(&selector.PID))
+	flags.StringVar(&selector.Name, "name", "", "device name")
+	flags.StringVar(&selector.Phys, "phys", "", "device physical location")
+	flags.StringVar(&selector.Uniq, "uniq", "", "device unique id")
+	hwidRegex := flags.String("hwid-regex", "", "regular expression matching the device hardware id")
+	grab := flags.Bool("grab", true, "keep the keystrokes from reaching other applications while recording")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go-barcode-relay record -o <file> <device selector>")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if *hwidRegex != "" {
+		var err error
+		selector.HWIDRegex, err = regexp.Compile(*hwidRegex)
+		if err != nil {
+			logger.Error("Invalid hwid regex: %s", err)
+			os.Exit(2)
+		}
+	}
+
+	if *output == "" || selector.IsEmpty() {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		logger.Error("Cannot create the recording: %s", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("Recording to %s, press Ctrl+C to stop", *output)
+
+	count, err := reader.Record(ctx, &selector, *grab, file)
+	if err != nil {
+		logger.Error("Recording failed after %d events: %s", count, err)
+		file.Close()
+		os.Exit(1)
+	}
+
+	logger.Info("Recorded %d events to %s", count, *output)
+}
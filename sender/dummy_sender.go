@@ -51,6 +51,11 @@ func (sender *DummySender) Run(
 			return
 		}
 
+		if scan.Symbology != "" {
+			logger.Info("Sent dummy message (%s, %s)\n", strings.ReplaceAll(scan.Content, "\n", ""), scan.Symbology)
+			continue
+		}
+
 		logger.Info("Sent dummy message (%s)\n", strings.ReplaceAll(scan.Content, "\n", ""))
 	}
 }
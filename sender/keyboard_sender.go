@@ -0,0 +1,277 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package sender
+
+import (
+	"fmt"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/logging"
+	"sirafino/go-barcode-relay/reader"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// The name of the virtual keyboard typing the scans
+const KeyboardDeviceName = reader.VirtualDevicePrefix + "keyboard"
+
+// Defaults of the keyboard target settings
+const (
+	DefaultKeyDelay  = 5 * time.Millisecond
+	DefaultSuffixKey = "enter"
+)
+
+// The keys which can be pressed after each scan
+var suffixKeys = map[string]uint16{
+	"enter": reader.KeyEnter,
+	"tab":   reader.KeyTab,
+	"none":  0,
+}
+
+// Time given to the system to pick up a new virtual keyboard, keystrokes
+// sent before are lost
+const keyboardSettleTime = 500 * time.Millisecond
+
+// Sender typing the scans on a virtual keyboard, as a keyboard wedge scanner
+// would, so that the relay can sit between a scanner and an application.
+//
+// The line terminators at the end of a scan are replaced by the suffix key.
+type KeyboardSender struct {
+	Layout    *reader.Layout
+	KeyDelay  time.Duration // Between two keystrokes
+	SuffixKey uint16        // Pressed after each scan, 0 for none
+
+	// What is typed for each scan, the scan content when nil. Executed with
+	// the code, device, symbology and relay of the scan and its fields.
+	Template *template.Template
+
+	// Where the keystrokes are sent, a new virtual keyboard when nil
+	Sink reader.EventSink
+
+	logger *logging.Logger
+}
+
+// Create a keyboard sender from its target configuration
+func NewKeyboardSender(target configuration.TargetConfiguration) (*KeyboardSender, error) {
+	if !reader.VirtualKeyboardSupported {
+		return nil, fmt.Errorf("the keyboard target is only supported on Linux")
+	}
+
+	layout := reader.LayoutUS
+	if target.Layout != "" {
+		var err error
+		layout, err = reader.GetLayout(target.Layout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if target.KeyDelayMs < 0 {
+		return nil, fmt.Errorf("key_delay_ms cannot be negative")
+	}
+
+	keyDelay := DefaultKeyDelay
+	if target.KeyDelayMs > 0 {
+		keyDelay = time.Duration(target.KeyDelayMs) * time.Millisecond
+	}
+
+	suffix := target.SuffixKey
+	if suffix == "" {
+		suffix = DefaultSuffixKey
+	}
+
+	suffixKey, ok := suffixKeys[suffix]
+	if !ok {
+		return nil, fmt.Errorf("unknown suffix_key %q (enter, tab or none)", suffix)
+	}
+
+	var tmpl *template.Template
+	if target.Template != "" {
+		var err error
+		tmpl, err = template.New("keyboard").Option("missingkey=zero").Parse(target.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &KeyboardSender{
+		Layout:    layout,
+		KeyDelay:  keyDelay,
+		SuffixKey: suffixKey,
+		Template:  tmpl,
+	}, nil
+}
+
+func (sender *KeyboardSender) Run(
+	scans chan reader.Scan,
+	relayID string,
+	wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+
+	if sender.logger == nil {
+		sender.logger = logging.GetLogger("SENDER")
+	}
+
+	defer func() {
+		if sender.Sink != nil {
+			sender.Sink.Close()
+		}
+	}()
+
+	for {
+		scan, ok := <-scans
+		if !ok {
+			sender.logger.Info("Stopping sender\n")
+			return
+		}
+
+		text, err := sender.text(scan, relayID)
+		if err != nil {
+			sender.logger.Error("Failed to type message: (%s, %s)\n", strings.ReplaceAll(scan.Content, "\n", ""), err)
+			continue
+		}
+
+		// Scans are never typed late, the virtual keyboard is created again
+		// for the next scan if needed
+		if sender.Sink == nil && !sender.openKeyboard() {
+			sender.logger.Error("Dropped message: (%s)\n", strings.ReplaceAll(scan.Content, "\n", ""))
+			continue
+		}
+
+		if err := sender.Type(text); err != nil {
+			sender.logger.Error("Failed to type message: (%s, %s)\n", strings.ReplaceAll(scan.Content, "\n", ""), err)
+			continue
+		}
+
+		sender.logger.Info("Typed message: (%s)\n", strings.ReplaceAll(scan.Content, "\n", ""))
+	}
+}
+
+// Create the virtual keyboard, able to press every key of the layout
+func (sender *KeyboardSender) openKeyboard() bool {
+	codes := []uint16{reader.KeyLeftShift, reader.KeyLeftCtrl, reader.KeyRightAlt}
+	for code := range sender.Layout.Keys {
+		codes = append(codes, code)
+	}
+
+	sink, err := reader.NewVirtualKeyboard(KeyboardDeviceName, codes)
+	if err != nil {
+		sender.logger.Error("Failed to create the virtual keyboard: %s\n", err)
+		return false
+	}
+
+	sender.logger.Info("Created the virtual keyboard\n")
+	sender.Sink = sink
+	time.Sleep(keyboardSettleTime)
+
+	return true
+}
+
+// The text typed for a scan
+func (sender *KeyboardSender) text(scan reader.Scan, relayID string) (string, error) {
+	code := strings.TrimRight(scan.Content, "\r\n\t")
+
+	if sender.Template == nil {
+		return code, nil
+	}
+
+	data := map[string]string{}
+	for name, value := range scan.Fields {
+		data[name] = value
+	}
+	data["code"] = code
+	data["device"] = scan.DeviceID
+	data["symbology"] = scan.Symbology
+	data["relay"] = relayID
+
+	var text strings.Builder
+	if err := sender.Template.Execute(&text, data); err != nil {
+		return "", err
+	}
+
+	return text.String(), nil
+}
+
+// Type a text followed by the suffix key. Nothing is typed when the layout
+// cannot type all of it.
+func (sender *KeyboardSender) Type(text string) error {
+	keystrokes, err := sender.Layout.Keystrokes(text)
+	if err != nil {
+		return err
+	}
+
+	if sender.SuffixKey != 0 {
+		keystrokes = append(keystrokes, reader.Keystroke{Code: sender.SuffixKey})
+	}
+
+	for i, keystroke := range keystrokes {
+		if i > 0 {
+			time.Sleep(sender.KeyDelay)
+		}
+
+		if err := sender.press(keystroke); err != nil {
+			// The virtual keyboard is created again for the next scan
+			sender.Sink.Close()
+			sender.Sink = nil
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Press and release a key along with its modifiers
+func (sender *KeyboardSender) press(keystroke reader.Keystroke) error {
+	var keys []uint16
+	if keystroke.Ctrl {
+		keys = append(keys, reader.KeyLeftCtrl)
+	}
+	if keystroke.Shift {
+		keys = append(keys, reader.KeyLeftShift)
+	}
+	if keystroke.AltGr {
+		keys = append(keys, reader.KeyRightAlt)
+	}
+	keys = append(keys, keystroke.Code)
+
+	for _, key := range keys {
+		if err := sender.write(key, 1); err != nil {
+			return err
+		}
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := sender.write(keys[i], 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write a key event followed by a synchronization event
+func (sender *KeyboardSender) write(code uint16, value int32) error {
+	err := sender.Sink.WriteEvent(reader.InputEvent{Type: reader.EventKey, Code: code, Value: value})
+	if err != nil {
+		return err
+	}
+
+	return sender.Sink.WriteEvent(reader.InputEvent{})
+}
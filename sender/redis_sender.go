@@ -73,14 +73,25 @@ func (sender *RedisStreamSender) Run(
 			scan = &s
 		}
 
+		values := map[string]any{
+			"relay":  relayID,
+			"device": scan.DeviceID,
+			"code":   scan.Content,
+			"ts":     scan.Timestamp,
+		}
+
+		if scan.Symbology != "" {
+			values["symbology"] = scan.Symbology
+		}
+
+		// Extra fields extracted by the reader (e.g. GS1 elements)
+		for name, value := range scan.Fields {
+			values[name] = value
+		}
+
 		cmd := client.XAdd(ctx, &redis.XAddArgs{
 			Stream: sender.Stream,
-			Values: map[string]any{
-				"relay":  relayID,
-				"device": scan.DeviceID,
-				"code":   scan.Content,
-				"ts":     scan.Timestamp,
-			},
+			Values: values,
 		})
 
 		err := cmd.Err()
@@ -0,0 +1,63 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"encoding/json"
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+// The listing is consumed by other tools, its field names must not change
+func TestDeviceDescriptorJSON(t *testing.T) {
+	descriptor := reader.DeviceDescriptor{
+		Path:         "/dev/input/event3",
+		Name:         "Scanner",
+		Phys:         "usb-0000:00:14.0-1/input0",
+		VID:          "0x0C2E",
+		PID:          "0x0B61",
+		Bus:          "usb",
+		Capabilities: []string{"EV_SYN", "EV_KEY"},
+		Grabbed:      true,
+		Reader:       "device01",
+	}
+
+	data, err := json.Marshal(descriptor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"path":"/dev/input/event3","name":"Scanner","phys":"usb-0000:00:14.0-1/input0","uniq":"",` +
+		`"vid":"0x0C2E","pid":"0x0B61","bus":"usb","capabilities":["EV_SYN","EV_KEY"],"grabbed":true,"reader":"device01"}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestKeyboards(t *testing.T) {
+	descriptors := []reader.DeviceDescriptor{
+		{Path: "/dev/input/event0", Capabilities: []string{"EV_SYN", "EV_KEY"}},
+		{Path: "/dev/input/event1", Capabilities: []string{"EV_SYN", "EV_REL"}},
+		{Path: "/dev/input/event2", Capabilities: []string{"EV_SYN", "EV_KEY", "EV_MSC", "EV_LED"}},
+	}
+
+	keyboards := reader.Keyboards(descriptors)
+	if len(keyboards) != 2 || keyboards[0].Path != "/dev/input/event0" || keyboards[1].Path != "/dev/input/event2" {
+		t.Errorf("unexpected keyboards %+v", keyboards)
+	}
+}
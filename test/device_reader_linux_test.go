@@ -0,0 +1,795 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"sirafino/go-barcode-relay/reader"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A scripted input device, its events are read in order and then it either
+// disconnects or waits to be closed
+type fakeDevice struct {
+	events    chan reader.InputEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+	grabErr   error
+}
+
+func newFakeDevice(events []reader.InputEvent, disconnect bool, grabErr error) *fakeDevice {
+	device := &fakeDevice{
+		events:  make(chan reader.InputEvent, len(events)),
+		closed:  make(chan struct{}),
+		grabErr: grabErr,
+	}
+
+	for _, event := range events {
+		device.events <- event
+	}
+
+	if disconnect {
+		close(device.events)
+	}
+
+	return device
+}
+
+func (device *fakeDevice) Grab() error {
+	return device.grabErr
+}
+
+func (device *fakeDevice) ReadEvent() (reader.InputEvent, error) {
+	select {
+	case event, ok := <-device.events:
+		if !ok {
+			return reader.InputEvent{}, errors.New("device disconnected")
+		}
+		return event, nil
+	case <-device.closed:
+		return reader.InputEvent{}, os.ErrClosed
+	}
+}
+
+func (device *fakeDevice) Path() string {
+	return "/dev/input/fake"
+}
+
+func (device *fakeDevice) Close() error {
+	device.closeOnce.Do(func() { close(device.closed) })
+	return nil
+}
+
+// Hands out the scripted devices in order, one per connection
+type fakeEvents struct {
+	mutex   sync.Mutex
+	devices []*fakeDevice
+	opened  int
+	sinks   []*fakeSink
+}
+
+func (events *fakeEvents) Open(selector *reader.DeviceSelector) (reader.EventDevice, error) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	if events.opened >= len(events.devices) {
+		return nil, reader.ErrDeviceNotFound
+	}
+
+	device := events.devices[events.opened]
+	events.opened++
+
+	return device, nil
+}
+
+func (events *fakeEvents) Watch(ctx context.Context) <-chan struct{} {
+	return make(chan struct{})
+}
+
+// Only used by the spawner, which has its own fake
+func (events *fakeEvents) List(selector *reader.DeviceSelector) ([]*reader.DeviceInfo, error) {
+	return nil, nil
+}
+
+func (events *fakeEvents) Mirror(device reader.EventDevice) (reader.EventSink, error) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	sink := &fakeSink{}
+	events.sinks = append(events.sinks, sink)
+
+	return sink, nil
+}
+
+// Collects the events sent back to the system in passthrough mode
+type fakeSink struct {
+	mutex  sync.Mutex
+	events []reader.InputEvent
+	closed bool
+}
+
+func (sink *fakeSink) WriteEvent(event reader.InputEvent) error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	// The time is set by the kernel
+	event.Time = 0
+	sink.events = append(sink.events, event)
+
+	return nil
+}
+
+func (sink *fakeSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	sink.closed = true
+	return nil
+}
+
+func (sink *fakeSink) written() []reader.InputEvent {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	return slices.Clone(sink.events)
+}
+
+type fakeDeviceScript struct {
+	events     []reader.InputEvent
+	disconnect bool
+	grabErr    error
+}
+
+func TestDeviceReader(t *testing.T) {
+	cases := []struct {
+		name    string
+		layout  *reader.Layout
+		regex   string
+		prefix  string // Delimited framing when set
+		suffix  string // Line feed when not set
+		devices []fakeDeviceScript
+		scans   []string
+	}{
+		{
+			name:    "letters and digits",
+			devices: []fakeDeviceScript{{events: typeText("abc123\n")}},
+			scans:   []string{"abc123\n"},
+		},
+		{
+			name: "shift and caps lock",
+			devices: []fakeDeviceScript{{events: concat(
+				typeText("Ab"), press(reader.KeyCapsLock), typeText("cD\n"),
+			)}},
+			scans: []string{"AbCd\n"},
+		},
+		{
+			name: "autorepeat and other events ignored",
+			devices: []fakeDeviceScript{{events: concat(
+				key(30, 1), key(30, 2), key(30, 2), key(30, 0),
+				[]reader.InputEvent{{Type: 4, Code: 4, Value: 0x70004}}, // EV_MSC scan code
+				typeText("\n"),
+			)}},
+			scans: []string{"a\n"},
+		},
+		{
+			name:   "german layout, altgr and dead keys",
+			layout: reader.LayoutDE,
+			devices: []fakeDeviceScript{{events: concat(
+				press(21), press(44),
+				chord(reader.KeyRightAlt, press(16)),
+				press(reader.KeyGrave), press(18),
+				typeText("\n"),
+			)}},
+			scans: []string{"zy@ê\n"},
+		},
+		{
+			name: "alt codes",
+			devices: []fakeDeviceScript{{events: concat(
+				chord(reader.KeyLeftAlt, press(reader.KeyKP0), press(reader.KeyKP2), press(reader.KeyKP9)),
+				typeText("A\n"),
+			)}},
+			scans: []string{"\x1dA\n"},
+		},
+		{
+			name:    "regex termination",
+			regex:   `^\d{4}`,
+			devices: []fakeDeviceScript{{events: typeText("12345678")}},
+			scans:   []string{"1234", "5678"},
+		},
+		{
+			name: "reconnection resets the modifiers",
+			devices: []fakeDeviceScript{
+				{events: concat(typeText("ab\n"), key(reader.KeyLeftShift, 1)), disconnect: true},
+				{events: typeText("cd\n")},
+			},
+			scans: []string{"ab\n", "cd\n"},
+		},
+		{
+			name:    "multi-byte prefix after noise",
+			layout:  reader.LayoutFR,
+			prefix:  "éé",
+			devices: []fakeDeviceScript{{events: concat(press(8), press(3), press(3), typeText("b\n"))}},
+			scans:   []string{"b"},
+		},
+		{
+			name:   "line breaks inside a frame",
+			prefix: "\x02",
+			suffix: "\x03",
+			devices: []fakeDeviceScript{{events: concat(
+				chord(reader.KeyLeftAlt, press(reader.KeyKP0), press(reader.KeyKP0), press(reader.KeyKP2)),
+				typeText("A\nB"),
+				chord(reader.KeyLeftAlt, press(reader.KeyKP0), press(reader.KeyKP1), press(reader.KeyKP3)),
+				typeText("\nC"),
+				chord(reader.KeyLeftAlt, press(reader.KeyKP0), press(reader.KeyKP0), press(reader.KeyKP3)),
+			)}},
+			scans: []string{"A\nB\r\nC"},
+		},
+		{
+			name: "grab failure",
+			devices: []fakeDeviceScript{
+				{events: typeText("xx\n"), grabErr: errors.New("device or resource busy")},
+				{events: typeText("ab\n")},
+			},
+			scans: []string{"ab\n"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			events := &fakeEvents{}
+			for _, script := range c.devices {
+				events.devices = append(events.devices, newFakeDevice(script.events, script.disconnect, script.grabErr))
+			}
+
+			regex := c.regex
+			if regex == "" {
+				regex = `.*?\n`
+			}
+
+			layout := c.layout
+			if layout == nil {
+				layout = reader.LayoutUS
+			}
+
+			deviceReader := &reader.DeviceReader{
+				DeviceID:    "fake",
+				Selector:    reader.DeviceSelector{Name: "fake"},
+				Layout:      layout,
+				Events:      events,
+				ScanOptions: reader.ScanOptions{Regex: regexp.MustCompile(regex)},
+			}
+
+			if c.prefix != "" {
+				deviceReader.Framing = reader.FramingDelimited
+				deviceReader.Prefix = c.prefix
+				deviceReader.Suffix = c.suffix
+				if deviceReader.Suffix == "" {
+					deviceReader.Suffix = "\n"
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			scans := make(chan reader.Scan, len(c.scans)+1)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				deviceReader.Run(ctx, scans)
+			}()
+
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+
+			for _, expected := range c.scans {
+				select {
+				case scan := <-scans:
+					if scan.Content != expected {
+						t.Errorf("expected %q, got %q", expected, scan.Content)
+					}
+				case <-time.After(3 * time.Second):
+					t.Fatalf("timed out waiting for %q", expected)
+				}
+			}
+
+			select {
+			case scan := <-scans:
+				t.Errorf("unexpected scan %q", scan.Content)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			if status := deviceReader.Status(); status != reader.StatusConnected {
+				t.Errorf("expected status %s, got %s", reader.StatusConnected, status)
+			}
+		})
+	}
+}
+
+func TestDeviceReaderShutdown(t *testing.T) {
+	cases := []struct {
+		name  string
+		flush bool
+		scans []string
+	}{
+		{"partial scan discarded", false, nil},
+		{"partial scan flushed", true, []string{"12"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// The device never disconnects, the pending read must be
+			// interrupted to stop
+			device := newFakeDevice(typeText("12"), false, nil)
+
+			deviceReader := &reader.DeviceReader{
+				DeviceID: "fake",
+				Selector: reader.DeviceSelector{Name: "fake"},
+				Layout:   reader.LayoutUS,
+				Events:   &fakeEvents{devices: []*fakeDevice{device}},
+				ScanOptions: reader.ScanOptions{
+					Regex:       regexp.MustCompile(`.*?\n`),
+					FlushOnIdle: c.flush,
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			scans := make(chan reader.Scan, 1)
+			stopped := make(chan struct{})
+
+			go func() {
+				defer close(stopped)
+				deviceReader.Run(ctx, scans)
+			}()
+
+			// Let the reader consume the events
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+				t.Fatal("the reader did not stop")
+			}
+
+			select {
+			case <-device.closed:
+			default:
+				t.Error("the device has not been closed")
+			}
+
+			if status := deviceReader.Status(); status != reader.StatusStopped {
+				t.Errorf("expected status %s, got %s", reader.StatusStopped, status)
+			}
+
+			close(scans)
+
+			received := []string{}
+			for scan := range scans {
+				received = append(received, scan.Content)
+			}
+
+			if !slices.Equal(received, c.scans) {
+				t.Errorf("expected scans %q, got %q", c.scans, received)
+			}
+		})
+	}
+}
+
+func TestAssembler(t *testing.T) {
+	idle := 30 * time.Millisecond
+
+	cases := []struct {
+		name    string
+		options reader.ScanOptions
+		devices []fakeDeviceScript
+		later   []reader.InputEvent // Typed on the last device once the idle timeout has expired
+		scans   []string
+	}{
+		{
+			name:    "idle flush",
+			options: reader.ScanOptions{IdleTimeout: idle, FlushOnIdle: true},
+			devices: []fakeDeviceScript{{events: typeText("12")}},
+			later:   typeText("34\n"),
+			scans:   []string{"12", "34\n"},
+		},
+		{
+			name:    "idle discard",
+			options: reader.ScanOptions{IdleTimeout: idle},
+			devices: []fakeDeviceScript{{events: typeText("12")}},
+			later:   typeText("34\n"),
+			scans:   []string{"34\n"},
+		},
+		{
+			name:    "max length overflow",
+			options: reader.ScanOptions{MaxLength: 3},
+			devices: []fakeDeviceScript{{events: typeText("12345\n67\n")}},
+			scans:   []string{"5\n", "67\n"},
+		},
+		{
+			name: "disconnection drops the partial scan",
+			devices: []fakeDeviceScript{
+				{events: typeText("ab"), disconnect: true},
+				{events: typeText("cd\n")},
+			},
+			scans: []string{"cd\n"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			events := &fakeEvents{}
+			for _, script := range c.devices {
+				events.devices = append(events.devices, newFakeDevice(script.events, script.disconnect, script.grabErr))
+			}
+
+			options := c.options
+			options.Regex = regexp.MustCompile(`.*?\n`)
+
+			deviceReader := &reader.DeviceReader{
+				DeviceID:    "fake",
+				Selector:    reader.DeviceSelector{Name: "fake"},
+				Layout:      reader.LayoutUS,
+				Events:      events,
+				ScanOptions: options,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			scans := make(chan reader.Scan, len(c.scans)+1)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				deviceReader.Run(ctx, scans)
+			}()
+
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+
+			if c.later != nil {
+				device := events.devices[len(events.devices)-1]
+
+				go func() {
+					time.Sleep(4 * idle)
+
+					for _, event := range c.later {
+						select {
+						case device.events <- event:
+						case <-device.closed:
+							return
+						}
+					}
+				}()
+			}
+
+			for _, expected := range c.scans {
+				select {
+				case scan := <-scans:
+					if scan.Content != expected {
+						t.Errorf("expected %q, got %q", expected, scan.Content)
+					}
+				case <-time.After(3 * time.Second):
+					t.Fatalf("timed out waiting for %q", expected)
+				}
+			}
+
+			select {
+			case scan := <-scans:
+				t.Errorf("unexpected scan %q", scan.Content)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+// Whether the reader is listed among the devices grabbed by the relay
+func grabbedByReader(readerID string) bool {
+	for _, descriptor := range reader.GrabbedDevices() {
+		if descriptor.Reader == readerID {
+			return descriptor.Path == "/dev/input/fake" && descriptor.Grabbed
+		}
+	}
+
+	return false
+}
+
+func TestGrabbedDevices(t *testing.T) {
+	device := newFakeDevice(nil, false, nil)
+
+	deviceReader := &reader.DeviceReader{
+		DeviceID: "grabbed",
+		Selector: reader.DeviceSelector{Name: "fake"},
+		Layout:   reader.LayoutUS,
+		Events:   &fakeEvents{devices: []*fakeDevice{device}},
+		ScanOptions: reader.ScanOptions{
+			Regex: regexp.MustCompile(`.*?\n`),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		deviceReader.Run(ctx, make(chan reader.Scan))
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !grabbedByReader("grabbed") {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("the device is not listed while grabbed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-stopped
+
+	if grabbedByReader("grabbed") {
+		t.Error("the device is still listed after the reader stopped")
+	}
+}
+
+// Set the time of the events, each key press happening step after the
+// previous one
+func timed(events []reader.InputEvent, start time.Duration, step time.Duration) []reader.InputEvent {
+	result := slices.Clone(events)
+	at := start
+
+	for i := range result {
+		if result[i].Type == reader.EventKey && result[i].Value == 1 {
+			at += step
+		}
+
+		result[i].Time = at.Microseconds()
+	}
+
+	return result
+}
+
+func TestDeviceReaderPassthrough(t *testing.T) {
+	scanner := 10 * time.Millisecond
+	typist := 150 * time.Millisecond
+
+	cases := []struct {
+		name     string
+		options  reader.ScanOptions
+		events   []reader.InputEvent
+		scans    []string
+		injected []reader.InputEvent
+	}{
+		{
+			name:     "scan then typing",
+			events:   concat(timed(typeText("1234\n"), 0, scanner), timed(typeText("a"), time.Second, typist)),
+			scans:    []string{"1234\n"},
+			injected: typeText("a"),
+		},
+		{
+			name:     "short burst",
+			events:   timed(typeText("ab"), 0, scanner),
+			injected: typeText("ab"),
+		},
+		{
+			name:     "slow typing",
+			events:   timed(typeText("Abcd\n"), 0, typist),
+			injected: typeText("Abcd\n"),
+		},
+		{
+			name: "typing then scan",
+			events: concat(
+				timed(typeText("ok"), 0, typist),
+				timed(typeText("56789\n"), time.Second, scanner),
+			),
+			scans:    []string{"56789\n"},
+			injected: typeText("ok"),
+		},
+		{
+			name: "short scan with a prefix",
+			options: reader.ScanOptions{
+				Framing: reader.FramingDelimited,
+				Prefix:  "#",
+				Suffix:  "\n",
+			},
+			events:   timed(concat(chord(reader.KeyLeftShift, press(4)), typeText("1\n")), 0, scanner),
+			scans:    []string{"1"},
+			injected: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			events := &fakeEvents{devices: []*fakeDevice{newFakeDevice(c.events, false, nil)}}
+
+			options := c.options
+			options.Regex = regexp.MustCompile(`.*?\n`)
+
+			deviceReader := &reader.DeviceReader{
+				DeviceID:    "fake",
+				Selector:    reader.DeviceSelector{Name: "fake"},
+				Layout:      reader.LayoutUS,
+				Events:      events,
+				ScanOptions: options,
+				Passthrough: &reader.PassthroughOptions{
+					MaxGap:    30 * time.Millisecond,
+					MinLength: 4,
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			scans := make(chan reader.Scan, len(c.scans)+1)
+			stopped := make(chan struct{})
+
+			go func() {
+				defer close(stopped)
+				deviceReader.Run(ctx, scans)
+			}()
+
+			for _, expected := range c.scans {
+				select {
+				case scan := <-scans:
+					if scan.Content != expected {
+						t.Errorf("expected %q, got %q", expected, scan.Content)
+					}
+				case <-time.After(3 * time.Second):
+					t.Fatalf("timed out waiting for %q", expected)
+				}
+			}
+
+			// The last burst is over once the max gap has passed
+			time.Sleep(100 * time.Millisecond)
+
+			select {
+			case scan := <-scans:
+				t.Errorf("unexpected scan %q", scan.Content)
+			default:
+			}
+
+			events.mutex.Lock()
+			sinks := slices.Clone(events.sinks)
+			events.mutex.Unlock()
+
+			if len(sinks) != 1 {
+				t.Fatalf("expected a virtual keyboard, got %d", len(sinks))
+			}
+
+			if injected := sinks[0].written(); !slices.Equal(injected, c.injected) {
+				t.Errorf("expected the events\n%v\nto be sent back, got\n%v", c.injected, injected)
+			}
+
+			cancel()
+			<-stopped
+
+			if !sinks[0].closed {
+				t.Error("the virtual keyboard has not been removed")
+			}
+		})
+	}
+}
+
+func TestDeviceReaderTiming(t *testing.T) {
+	scanner := 5 * time.Millisecond
+	typist := 30 * time.Millisecond
+
+	cases := []struct {
+		name   string
+		action string
+		step   time.Duration
+		scans  []string
+		field  string
+		event  string
+	}{
+		{name: "scanner", step: scanner, scans: []string{"1234\n"}},
+		{name: "typist dropped", step: typist, event: reader.TimingHuman},
+		{name: "typist flagged", action: reader.InvalidFlag, step: typist, scans: []string{"1234\n"}, field: reader.TimingHuman, event: reader.TimingHuman},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			deviceID := "timing " + c.name
+
+			events := make(chan reader.SecurityEvent, 1)
+			// Hooks cannot be removed, the ones of previous runs must not block
+			reader.OnSecurityEvent(func(event reader.SecurityEvent) {
+				if event.DeviceID != deviceID {
+					return
+				}
+
+				select {
+				case events <- event:
+				default:
+				}
+			})
+
+			// The events are all read at once, as when the reader lags
+			// behind the device: only their time tells how they were typed
+			start := time.Duration(time.Now().UnixNano())
+			device := newFakeDevice(timed(typeText("1234\n"), start, c.step), false, nil)
+
+			deviceReader := &reader.DeviceReader{
+				DeviceID: deviceID,
+				Selector: reader.DeviceSelector{Name: "fake"},
+				Layout:   reader.LayoutUS,
+				Events:   &fakeEvents{devices: []*fakeDevice{device}},
+				ScanOptions: reader.ScanOptions{
+					Regex: regexp.MustCompile(`.*?\n`),
+					Timing: &reader.TimingOptions{
+						HumanInterval: 15 * time.Millisecond,
+						MinInterval:   time.Millisecond,
+						Tolerance:     reader.DefaultTolerance,
+						Action:        c.action,
+					},
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			scans := make(chan reader.Scan, 1)
+			go deviceReader.Run(ctx, scans)
+
+			for _, expected := range c.scans {
+				select {
+				case scan := <-scans:
+					if scan.Content != expected {
+						t.Errorf("expected %q, got %q", expected, scan.Content)
+					}
+					if scan.Fields["timing"] != c.field {
+						t.Errorf("expected timing field %q, got %q", c.field, scan.Fields["timing"])
+					}
+				case <-time.After(3 * time.Second):
+					t.Fatalf("timed out waiting for %q", expected)
+				}
+			}
+
+			if c.event == "" {
+				select {
+				case event := <-events:
+					t.Errorf("unexpected security event %+v", event)
+				default:
+				}
+				return
+			}
+
+			select {
+			case event := <-events:
+				if event.Kind != c.event || event.Content != "1234\n" || len(event.Intervals) != 4 {
+					t.Errorf("unexpected security event %+v", event)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatalf("timed out waiting for the security event")
+			}
+
+			select {
+			case scan := <-scans:
+				t.Errorf("unexpected scan %q", scan.Content)
+			default:
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/reader"
+	"strings"
+	"testing"
+)
+
+func TestInferKeyboardProfile(t *testing.T) {
+	cases := []struct {
+		name       string
+		events     []reader.InputEvent
+		expected   string
+		layout     string
+		content    string
+		terminator string
+		valid      bool
+	}{
+		{"enter terminated", typeText("Abc123\n"), "", "us", "Abc123", "\n", true},
+		{"no terminator", typeText("4006381333931"), "", "us", "4006381333931", "", true},
+		{"tab and enter", concat(typeText("12"), press(reader.KeyTab), typeText("\n")), "", "us", "12", "\t\n", true},
+		{"expected content", typeText("12y\n"), "12y", "us", "12y", "\n", true},
+		{"german layout", typeText("12y\n"), "12z", "de", "12z", "\n", true},
+		{"unexpected content", typeText("12\n"), "34", "", "", "", false},
+		{"no characters", key(reader.KeyLeftShift, 1), "", "", "", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			profile, err := reader.InferKeyboardProfile(c.events, c.expected)
+			if !c.valid {
+				if err == nil {
+					t.Errorf("expected an error, got layout %s", profile.Layout.Name)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if profile.Layout.Name != c.layout || profile.Content != c.content || profile.Terminator != c.terminator {
+				t.Errorf("expected %s %q %q, got %s %q %q", c.layout, c.content, c.terminator,
+					profile.Layout.Name, profile.Content, profile.Terminator)
+			}
+		})
+	}
+}
+
+func TestUniqueSelector(t *testing.T) {
+	scanner := &reader.DeviceInfo{Path: "/dev/input/event3", Name: "Scanner", Phys: "usb-1/input0", VID: 0x0C2E, PID: 0x0B61}
+	consumer := &reader.DeviceInfo{Path: "/dev/input/event4", Name: "Scanner Consumer Control", Phys: "usb-1/input1", VID: 0x0C2E, PID: 0x0B61}
+	twin := &reader.DeviceInfo{Path: "/dev/input/event5", Name: "Scanner", Phys: "usb-2/input0", VID: 0x0C2E, PID: 0x0B61}
+	keyboard := &reader.DeviceInfo{Path: "/dev/input/event1", Name: "Keyboard", VID: 0x046D, PID: 0xC31C}
+
+	cases := []struct {
+		name     string
+		devices  []*reader.DeviceInfo
+		selector reader.DeviceSelector
+	}{
+		{"unique ids", []*reader.DeviceInfo{keyboard, scanner}, reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61}},
+		{"several interfaces", []*reader.DeviceInfo{keyboard, scanner, consumer}, reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61, Name: "Scanner"}},
+		{"identical scanners", []*reader.DeviceInfo{scanner, consumer, twin}, reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61, Name: "Scanner", Phys: "usb-1/input0"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			selector := reader.UniqueSelector(scanner, c.devices)
+			if selector != c.selector {
+				t.Errorf("expected %+v, got %+v", c.selector, selector)
+			}
+		})
+	}
+}
+
+func TestAppendDeviceYAML(t *testing.T) {
+	device := configuration.DeviceConfiguration{
+		ID:            "scanner01",
+		Type:          "evdev",
+		VID:           0x0C2E,
+		PID:           0x0B61,
+		Layout:        "de",
+		FullScanRegex: `.*?\r\n`,
+	}
+
+	block := strings.Join([]string{
+		"- id: scanner01",
+		"  type: evdev",
+		"  vid: 0x0C2E",
+		"  pid: 0x0B61",
+		"  full_scan_regex: .*?\\r\\n",
+		"  layout: de",
+	}, "\n")
+
+	indented := "  " + strings.ReplaceAll(block, "\n", "\n  ")
+
+	cases := []struct {
+		name     string
+		content  string
+		expected string
+		valid    bool
+	}{
+		{"new file", "", "devices:\n" + indented + "\n", true},
+		{
+			"no devices",
+			"id: relay01\n",
+			"id: relay01\ndevices:\n" + indented + "\n",
+			true,
+		},
+		{
+			"empty devices",
+			"id: relay01\ndevices:\ntarget:\n  type: dummy\n",
+			"id: relay01\ndevices:\n" + indented + "\ntarget:\n  type: dummy\n",
+			true,
+		},
+		{
+			"before the next key and its comments",
+			"devices:\n  - id: old\n    # The reader\n    vid: 1\n\n# Where scans go\ntarget:\n  type: dummy\n",
+			"devices:\n  - id: old\n    # The reader\n    vid: 1\n" + indented + "\n\n# Where scans go\ntarget:\n  type: dummy\n",
+			true,
+		},
+		{
+			"last key, unindented list",
+			"id: relay01\ndevices:\n- id: old\n  vid: 1\n",
+			"id: relay01\ndevices:\n- id: old\n  vid: 1\n" + block + "\n",
+			true,
+		},
+		{"duplicate id", "devices:\n  - id: scanner01\n", "", false},
+		{"flow list", "devices: [{id: old}]\n", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := configuration.AppendDeviceYAML([]byte(c.content), device)
+			if !c.valid {
+				if err == nil {
+					t.Errorf("expected an error, got %q", result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if string(result) != c.expected {
+				t.Errorf("expected\n%s\ngot\n%s", c.expected, result)
+			}
+		})
+	}
+}
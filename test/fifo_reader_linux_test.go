@@ -0,0 +1,49 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+func TestFIFOReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.fifo")
+
+	fifoReader := &reader.FIFOReader{
+		DeviceID: "fifo",
+		Path:     path,
+	}
+
+	write := func() {
+		// Writers can come and go, each one is read until it closes the pipe
+		for _, lines := range []string{"ABC\n", "DEF\nGHI\n"} {
+			fifo, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fifo.WriteString(lines)
+			fifo.Close()
+		}
+	}
+
+	expectScans(t, fifoReader, write, []string{"ABC", "DEF", "GHI"})
+}
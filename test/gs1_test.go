@@ -0,0 +1,67 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+func TestParseGS1(t *testing.T) {
+	cases := []struct {
+		code     string
+		expected map[string]string
+		valid    bool
+	}{
+		{"]C101095011010209171719050810ABCD1234\n", map[string]string{"01": "09501101020917", "17": "190508", "10": "ABCD1234"}, true},
+		{"\x1d0109501101020917" + "10LOT1\x1d21SER1", map[string]string{"01": "09501101020917", "10": "LOT1", "21": "SER1"}, true},
+		{"]d2010950110102091731030012503712", map[string]string{"01": "09501101020917", "3103": "001250", "37": "12"}, true},
+		{"(01)09501101020917(17)190508", map[string]string{"01": "09501101020917", "17": "190508"}, true},
+		{"010950110102091", nil, false},              // Truncated GTIN
+		{"0109501101020917171913", nil, false},       // Truncated date
+		{"0109501101020917171399", nil, false},       // Invalid month
+		{"0109501101020917051234", nil, false},       // Unknown AI
+		{"10" + "123456789012345678901", nil, false}, // Batch too long
+	}
+
+	for _, c := range cases {
+		elements, err := reader.ParseGS1(c.code)
+		if !c.valid {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", c.code, elements)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.code, err)
+			continue
+		}
+
+		if len(elements) != len(c.expected) {
+			t.Errorf("%q: expected %d elements, got %v", c.code, len(c.expected), elements)
+			continue
+		}
+
+		for _, element := range elements {
+			if c.expected[element.AI] != element.Value {
+				t.Errorf("%q: AI (%s) expected %q, got %q", c.code, element.AI, c.expected[element.AI], element.Value)
+			}
+		}
+	}
+}
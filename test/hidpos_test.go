@@ -0,0 +1,125 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+// Build a 64 bytes HID POS scanned data report
+func hidposReport(reportID byte, aim string, data string, continued bool) []byte {
+	report := make([]byte, 64)
+	report[0] = reportID
+	report[1] = byte(len(data))
+	copy(report[2:5], aim)
+	copy(report[5:], data)
+
+	if continued {
+		report[63] = 0x01
+	}
+
+	return report
+}
+
+func TestHIDPOSDecoder(t *testing.T) {
+	long := "0123456789012345678901234567890123456789012345678901234"
+
+	cases := []struct {
+		name      string
+		reports   [][]byte
+		aim       string
+		data      string
+		symbology string
+	}{
+		{
+			"single report",
+			[][]byte{hidposReport(0x02, "]E0", "4006381333931", false)},
+			"]E0", "4006381333931", "EAN-13",
+		},
+		{
+			"aim without flag character",
+			[][]byte{hidposReport(0x02, "Q1", "https://example.com", false)},
+			"]Q1", "https://example.com", "QR Code",
+		},
+		{
+			"no aim",
+			[][]byte{hidposReport(0x02, "", "ABC", false)},
+			"", "ABC", "",
+		},
+		{
+			"multiple reports",
+			[][]byte{
+				hidposReport(0x02, "]d2", long, true),
+				hidposReport(0x02, "", "tail", false),
+			},
+			"]d2", long + "tail", "GS1 DataMatrix",
+		},
+		{
+			"other reports ignored",
+			[][]byte{
+				hidposReport(0x04, "", "status", false),
+				hidposReport(0x02, "]C0", "CODE", false),
+			},
+			"]C0", "CODE", "Code 128",
+		},
+	}
+
+	for _, c := range cases {
+		decoder := reader.HIDPOSDecoder{}
+
+		var barcode *reader.HIDPOSReport
+		for i, report := range c.reports {
+			result, err := decoder.Push(report)
+			if err != nil {
+				t.Fatalf("%s: %s", c.name, err)
+			}
+
+			if result != nil && i != len(c.reports)-1 {
+				t.Fatalf("%s: barcode completed after report %d", c.name, i)
+			}
+
+			barcode = result
+		}
+
+		if barcode == nil {
+			t.Fatalf("%s: no barcode", c.name)
+		}
+
+		if barcode.AIM != c.aim || string(barcode.Data) != c.data {
+			t.Errorf("%s: got %q %q, expected %q %q", c.name, barcode.AIM, barcode.Data, c.aim, c.data)
+		}
+
+		if barcode.Symbology() != c.symbology {
+			t.Errorf("%s: got symbology %q, expected %q", c.name, barcode.Symbology(), c.symbology)
+		}
+	}
+}
+
+func TestParseHIDPOSReportInvalid(t *testing.T) {
+	report := hidposReport(0x02, "]E0", "123", false)
+	report[1] = 63
+
+	if _, err := reader.ParseHIDPOSReport(report); err == nil {
+		t.Error("expected an error for a data length exceeding the report")
+	}
+
+	if _, err := reader.ParseHIDPOSReport([]byte{0x02, 0x00}); err == nil {
+		t.Error("expected an error for a short report")
+	}
+}
@@ -0,0 +1,215 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"net/http"
+	"sirafino/go-barcode-relay/reader"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run an HTTP reader until the test ends, returning the scans URL
+func startHTTPReader(t *testing.T, httpReader *reader.HTTPReader, scans chan reader.Scan) string {
+	httpReader.Address = freeAddress(t, "tcp")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		httpReader.Run(ctx, scans)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	for httpReader.Status() != reader.StatusConnected {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return "http://" + httpReader.Address + "/scans"
+}
+
+func postScan(t *testing.T, url string, token string, contentType string, body string) int {
+	request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Header.Set("Content-Type", contentType)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	return response.StatusCode
+}
+
+func TestHTTPReader(t *testing.T) {
+	validator, err := reader.NewValidator([]string{"ean13"}, nil, reader.InvalidDrop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scans := make(chan reader.Scan, 10)
+	url := startHTTPReader(t, &reader.HTTPReader{
+		DeviceID:    "http",
+		Tokens:      map[string]string{"phone-token": "phone01", "other-token": "phone02"},
+		ScanOptions: reader.ScanOptions{Validator: validator},
+	}, scans)
+
+	cases := []struct {
+		name        string
+		token       string
+		contentType string
+		body        string
+		status      int
+		device      string
+	}{
+		{"json", "phone-token", "application/json", `{"content": "4006381333931"}`, http.StatusAccepted, "phone01"},
+		{"form", "other-token", "application/x-www-form-urlencoded", "content=4006381333931", http.StatusAccepted, "phone02"},
+		{"missing token", "", "application/json", `{"content": "4006381333931"}`, http.StatusUnauthorized, ""},
+		{"wrong token", "nope", "application/json", `{"content": "4006381333931"}`, http.StatusUnauthorized, ""},
+		{"unknown field", "phone-token", "application/json", `{"code": "4006381333931"}`, http.StatusBadRequest, ""},
+		{"missing content", "phone-token", "application/x-www-form-urlencoded", "code=1", http.StatusBadRequest, ""},
+		{"content type", "phone-token", "text/plain", "4006381333931", http.StatusUnsupportedMediaType, ""},
+		{"too large", "phone-token", "application/json", `{"content": "` + strings.Repeat("1", 70000) + `"}`, http.StatusRequestEntityTooLarge, ""},
+		{"invalid scan", "phone-token", "application/json", `{"content": "4006381333932"}`, http.StatusUnprocessableEntity, ""},
+	}
+
+	for _, c := range cases {
+		status := postScan(t, url, c.token, c.contentType, c.body)
+		if status != c.status {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.status, status)
+			continue
+		}
+
+		if c.device == "" {
+			continue
+		}
+
+		select {
+		case scan := <-scans:
+			if scan.DeviceID != c.device || scan.Content != "4006381333931" {
+				t.Errorf("%s: unexpected scan %s %q", c.name, scan.DeviceID, scan.Content)
+			}
+		default:
+			t.Errorf("%s: no scan received", c.name)
+		}
+	}
+
+	if len(scans) != 0 {
+		t.Errorf("unexpected scans: %d", len(scans))
+	}
+}
+
+func TestHTTPReaderRateLimit(t *testing.T) {
+	scans := make(chan reader.Scan, 10)
+	url := startHTTPReader(t, &reader.HTTPReader{
+		DeviceID:  "http",
+		Tokens:    map[string]string{"phone-token": "phone01", "other-token": "phone02"},
+		RateLimit: 0.001,
+		Burst:     2,
+	}, scans)
+
+	// Each token has its own limit
+	expected := []struct {
+		token  string
+		status int
+	}{
+		{"phone-token", http.StatusAccepted},
+		{"phone-token", http.StatusAccepted},
+		{"phone-token", http.StatusTooManyRequests},
+		{"other-token", http.StatusAccepted},
+	}
+
+	for i, e := range expected {
+		status := postScan(t, url, e.token, "application/json", `{"content": "ABC"}`)
+		if status != e.status {
+			t.Errorf("request %d: expected status %d, got %d", i, e.status, status)
+		}
+	}
+
+	if len(scans) != 3 {
+		t.Errorf("expected 3 scans, got %d", len(scans))
+	}
+}
+
+func TestHTTPReaderShutdown(t *testing.T) {
+	httpReader := &reader.HTTPReader{
+		DeviceID: "http",
+		Address:  freeAddress(t, "tcp"),
+		Tokens:   map[string]string{"phone-token": "phone01"},
+	}
+
+	// Nobody reads the scans, the request waits until the reader stops
+	scans := make(chan reader.Scan)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		httpReader.Run(ctx, scans)
+	}()
+
+	for httpReader.Status() != reader.StatusConnected {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// postScan cannot be used, t.Fatal must not be called from another goroutine
+	status := make(chan int, 1)
+	go func() {
+		request, _ := http.NewRequest(http.MethodPost, "http://"+httpReader.Address+"/scans", strings.NewReader(`{"content": "4006381333931"}`))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer phone-token")
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			status <- 0
+			return
+		}
+		response.Body.Close()
+
+		status <- response.StatusCode
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the reader did not stop")
+	}
+
+	if received := <-status; received != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, received)
+	}
+}
@@ -0,0 +1,122 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/reader"
+	"sirafino/go-barcode-relay/sender"
+	"sync"
+	"testing"
+)
+
+func TestKeyboardSender(t *testing.T) {
+	scan := reader.Scan{
+		DeviceID:  "scanner",
+		Content:   "0109501101530003\x1d10AB12\n",
+		Symbology: "GS1-128",
+		Fields:    map[string]string{"gs1_01": "09501101530003", "gs1_10": "AB12"},
+	}
+
+	tests := []struct {
+		name     string
+		target   configuration.TargetConfiguration
+		expected string
+		err      bool
+	}{
+		{
+			name:     "defaults",
+			target:   configuration.TargetConfiguration{Type: "keyboard"},
+			expected: "0109501101530003\x1d10AB12\n",
+		},
+		{
+			name:     "tab suffix",
+			target:   configuration.TargetConfiguration{Type: "keyboard", SuffixKey: "tab"},
+			expected: "0109501101530003\x1d10AB12\t",
+		},
+		{
+			name:     "no suffix",
+			target:   configuration.TargetConfiguration{Type: "keyboard", SuffixKey: "none"},
+			expected: "0109501101530003\x1d10AB12",
+		},
+		{
+			name:     "template",
+			target:   configuration.TargetConfiguration{Type: "keyboard", Template: "{{.gs1_01}}\t{{.gs1_10}}{{.missing}}"},
+			expected: "09501101530003\tAB12\n",
+		},
+		{
+			name:     "layout",
+			target:   configuration.TargetConfiguration{Type: "keyboard", Layout: "de", Template: "{{.device}}@{{.relay}}"},
+			expected: "scanner@relay\n",
+		},
+		{
+			name:   "unknown layout",
+			target: configuration.TargetConfiguration{Type: "keyboard", Layout: "xx"},
+			err:    true,
+		},
+		{
+			name:   "unknown suffix key",
+			target: configuration.TargetConfiguration{Type: "keyboard", SuffixKey: "space"},
+			err:    true,
+		},
+		{
+			name:   "negative delay",
+			target: configuration.TargetConfiguration{Type: "keyboard", KeyDelayMs: -1},
+			err:    true,
+		},
+		{
+			name:   "invalid template",
+			target: configuration.TargetConfiguration{Type: "keyboard", Template: "{{.code"},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyboard, err := sender.NewKeyboardSender(test.target)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sink := &keystrokeSink{}
+			keyboard.Sink = sink
+			keyboard.KeyDelay = 0
+
+			scans := make(chan reader.Scan, 1)
+			scans <- scan
+			close(scans)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			keyboard.Run(scans, "relay", &wg)
+
+			if got := decodeWritten(keyboard.Layout, sink.events); got != test.expected {
+				t.Errorf("got %q, expected %q", got, test.expected)
+			}
+			if !sink.closed {
+				t.Errorf("expected the virtual keyboard to be closed")
+			}
+		})
+	}
+}
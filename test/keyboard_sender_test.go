@@ -0,0 +1,102 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"sirafino/go-barcode-relay/sender"
+	"testing"
+)
+
+// Decode the key events written to a sink with a layout
+func decodeWritten(layout *reader.Layout, events []reader.InputEvent) string {
+	keyboard := reader.Keyboard{Layout: layout}
+
+	var text string
+	for _, event := range events {
+		text += keyboard.HandleEvent(event.Type, event.Code, event.Value)
+	}
+
+	return text
+}
+
+func TestKeystrokes(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		text   string
+		err    bool
+	}{
+		{name: "us", layout: "us", text: "Hello, World! 123-456_789"},
+		{name: "us symbols", layout: "us", text: "~`!@#$%^&*()[]{}\\|;:'\",.<>/?"},
+		{name: "gs separator", layout: "us", text: "010123\x1d10ABC"},
+		{name: "uk", layout: "uk", text: "£5 @ #1 \"é\""},
+		{name: "de altgr", layout: "de", text: "user@example.com {x} ß"},
+		{name: "de dead keys", layout: "de", text: "Grüße ^ ´ â"},
+		{name: "fr", layout: "fr", text: "AZERTY 0123456789 ê ë è"},
+		{name: "es", layout: "es", text: "Año ñ ¿? ü"},
+		{name: "missing character", layout: "us", text: "café", err: true},
+		{name: "combining mark", layout: "de", text: "a" + reader.DeadAcute, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout, err := reader.GetLayout(test.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sink := &keystrokeSink{}
+			keyboard := sender.KeyboardSender{Layout: layout, Sink: sink}
+
+			err = keyboard.Type(test.text)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				if len(sink.events) > 0 {
+					t.Errorf("expected nothing typed, got %d events", len(sink.events))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := decodeWritten(layout, sink.events); got != test.text {
+				t.Errorf("got %q, expected %q", got, test.text)
+			}
+		})
+	}
+}
+
+// Collects the events typed by a keyboard sender
+type keystrokeSink struct {
+	events []reader.InputEvent
+	closed bool
+}
+
+func (sink *keystrokeSink) WriteEvent(event reader.InputEvent) error {
+	sink.events = append(sink.events, event)
+	return nil
+}
+
+func (sink *keystrokeSink) Close() error {
+	sink.closed = true
+	return nil
+}
@@ -0,0 +1,74 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"slices"
+)
+
+// Codes of the letter and digit keys
+var keyCodes = map[rune]uint16{
+	'q': 16, 'w': 17, 'e': 18, 'r': 19, 't': 20, 'y': 21, 'u': 22, 'i': 23, 'o': 24, 'p': 25,
+	'a': 30, 's': 31, 'd': 32, 'f': 33, 'g': 34, 'h': 35, 'j': 36, 'k': 37, 'l': 38,
+	'z': 44, 'x': 45, 'c': 46, 'v': 47, 'b': 48, 'n': 49, 'm': 50,
+	'1': 2, '2': 3, '3': 4, '4': 5, '5': 6, '6': 7, '7': 8, '8': 9, '9': 10, '0': 11,
+	'\n': reader.KeyEnter,
+}
+
+func key(code uint16, value int32) []reader.InputEvent {
+	return []reader.InputEvent{
+		{Type: reader.EventKey, Code: code, Value: value},
+		{Type: 0, Code: 0, Value: 0}, // EV_SYN
+	}
+}
+
+// Press and release a key
+func press(code uint16) []reader.InputEvent {
+	return append(key(code, 1), key(code, 0)...)
+}
+
+// Hold a modifier while the events happen
+func chord(modifier uint16, events ...[]reader.InputEvent) []reader.InputEvent {
+	result := key(modifier, 1)
+	for _, e := range events {
+		result = append(result, e...)
+	}
+
+	return append(result, key(modifier, 0)...)
+}
+
+// Type text on a US keyboard, upper case letters are typed with shift
+func typeText(text string) []reader.InputEvent {
+	events := []reader.InputEvent{}
+
+	for _, c := range text {
+		if c >= 'A' && c <= 'Z' {
+			events = append(events, chord(reader.KeyLeftShift, press(keyCodes[c-'A'+'a']))...)
+			continue
+		}
+
+		events = append(events, press(keyCodes[c])...)
+	}
+
+	return events
+}
+
+func concat(events ...[]reader.InputEvent) []reader.InputEvent {
+	return slices.Concat(events...)
+}
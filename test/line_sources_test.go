@@ -0,0 +1,110 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sirafino/go-barcode-relay/reader"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run a source until the expected scans are received
+func expectScans(t *testing.T, source reader.Source, write func(), expected []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scans := make(chan reader.Scan, len(expected)+1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		source.Run(ctx, scans)
+	}()
+
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Let the source open its input before writing
+	time.Sleep(300 * time.Millisecond)
+	write()
+
+	for _, content := range expected {
+		select {
+		case scan := <-scans:
+			if scan.Content != content {
+				t.Errorf("expected %q, got %q", content, scan.Content)
+			}
+			if scan.DeviceID != source.ID() {
+				t.Errorf("expected device %s, got %s", source.ID(), scan.DeviceID)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %q", content)
+		}
+	}
+}
+
+func TestTailReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.log")
+
+	// Lines already in the file are not read
+	if err := os.WriteFile(path, []byte("1234567890128\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := reader.NewValidator([]string{"ean13", "ean8"}, nil, reader.InvalidDrop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tailReader := &reader.TailReader{
+		DeviceID:    "tail",
+		Path:        path,
+		ScanOptions: reader.ScanOptions{Validator: validator},
+	}
+
+	write := func() {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The second line is incomplete until the next write, the third
+		// fails validation
+		file.WriteString("4006381333931\r\n9638")
+		time.Sleep(400 * time.Millisecond)
+		file.WriteString("5074\nnot a barcode\n")
+		file.Close()
+
+		time.Sleep(400 * time.Millisecond)
+
+		// Rotate the file, the new one is read from the beginning
+		if err := os.Rename(path, path+".1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("4006381333931\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectScans(t, tailReader, write, []string{"4006381333931", "96385074", "4006381333931"})
+}
@@ -0,0 +1,144 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"net"
+	"sirafino/go-barcode-relay/reader"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Get a free local port for the listener under test
+func freeAddress(t *testing.T, network string) string {
+	if network == reader.NetworkUDP {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		return conn.LocalAddr().String()
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// Run a network reader, send data to it and check the scans received
+func runNetworkReader(t *testing.T, networkReader *reader.NetworkReader, send func(address string), expected []reader.Scan) {
+	networkReader.Address = freeAddress(t, networkReader.Network)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scans := make(chan reader.Scan, len(expected)+1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		networkReader.Run(ctx, scans)
+	}()
+
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Wait for the listener to be up
+	for networkReader.Status() != reader.StatusConnected {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	send(networkReader.Address)
+
+	for _, scan := range expected {
+		select {
+		case received := <-scans:
+			if received.DeviceID != scan.DeviceID || received.Content != scan.Content {
+				t.Errorf("expected %s %q, got %s %q", scan.DeviceID, scan.Content, received.DeviceID, received.Content)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %q", scan.Content)
+		}
+	}
+}
+
+func TestNetworkReaderTCP(t *testing.T) {
+	networkReader := &reader.NetworkReader{
+		DeviceID: "network",
+		Tokens:   map[string]string{"secret": "conveyor01"},
+	}
+
+	send := func(address string) {
+		// Clients with an invalid token are dropped
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("wrong\r\nIGNORED\r\n"))
+		conn.Close()
+
+		conn, err = net.Dial("tcp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("secret\r\nABC\r\n"))
+		conn.Write([]byte("DE"))
+		time.Sleep(50 * time.Millisecond)
+		conn.Write([]byte("F\n"))
+	}
+
+	runNetworkReader(t, networkReader, send, []reader.Scan{
+		{DeviceID: "conveyor01", Content: "ABC"},
+		{DeviceID: "conveyor01", Content: "DEF"},
+	})
+}
+
+func TestNetworkReaderUDP(t *testing.T) {
+	networkReader := &reader.NetworkReader{
+		DeviceID:  "network",
+		Network:   reader.NetworkUDP,
+		Frame:     reader.FrameLength,
+		RemoteIDs: map[string]string{"127.0.0.1": "local"},
+	}
+
+	send := func(address string) {
+		conn, err := net.Dial("udp", address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		// Two length prefixed frames in a single datagram
+		conn.Write([]byte("\x00\x03ABC\x00\x04A\nBC"))
+	}
+
+	runNetworkReader(t, networkReader, send, []reader.Scan{
+		{DeviceID: "local", Content: "ABC"},
+		{DeviceID: "local", Content: "A\nBC"},
+	})
+}
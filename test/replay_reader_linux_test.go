@@ -0,0 +1,142 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/reader"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A recording of "Ab1" followed by Enter, the events are 20 ms apart
+func writeRecording(t *testing.T) string {
+	keys := []struct {
+		code  uint16
+		value int32
+	}{
+		{reader.KeyLeftShift, 1}, {30, 1}, {30, 2}, {30, 0}, {reader.KeyLeftShift, 0},
+		{48, 1}, {48, 0}, {2, 1}, {2, 0}, {28, 1}, {28, 0},
+	}
+
+	var recording bytes.Buffer
+	start := time.Now().UnixMicro()
+
+	for i, key := range keys {
+		at := start + int64(i)*20000
+
+		// Each key event is followed by a synchronization event
+		events := []reader.InputEvent{
+			{Time: at, Type: reader.EventKey, Code: key.code, Value: key.value},
+			{Time: at, Type: 0, Code: 0, Value: 0},
+		}
+
+		for _, event := range events {
+			if err := reader.WriteInputEvent(&recording, event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "scanner.rec")
+	if err := os.WriteFile(path, recording.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReplayReader(t *testing.T) {
+	path := writeRecording(t)
+
+	for _, speed := range []float64{1, 100} {
+		replayReader := &reader.DeviceReader{
+			DeviceID: "replay",
+			Layout:   reader.LayoutUS,
+			ScanOptions: reader.ScanOptions{
+				Framing: reader.FramingDelimited,
+				Suffix:  "\n",
+			},
+			Events: &reader.ReplayEvents{DeviceID: "replay", Path: path, Speed: speed},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		scans := make(chan reader.Scan, 1)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replayReader.Run(ctx, scans)
+		}()
+
+		start := time.Now()
+
+		select {
+		case scan := <-scans:
+			if scan.Content != "Ab1" {
+				t.Errorf("speed %v: expected %q, got %q", speed, "Ab1", scan.Content)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("speed %v: timed out", speed)
+		}
+
+		// The recording lasts 200 ms at the original speed
+		elapsed := time.Since(start)
+		if speed == 1 && elapsed < 150*time.Millisecond {
+			t.Errorf("replayed too fast at the original speed: %s", elapsed)
+		}
+		if speed == 100 && elapsed > 150*time.Millisecond {
+			t.Errorf("replayed too slow at speed 100: %s", elapsed)
+		}
+
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestReplaySource(t *testing.T) {
+	path := writeRecording(t)
+
+	config := configuration.DeviceConfiguration{
+		ID:     "replay",
+		Type:   reader.SourceReplay,
+		Path:   path,
+		Timing: &configuration.TimingConfiguration{},
+	}
+
+	// Replayed keystrokes go through the device reader, timing included
+	source, err := reader.NewSource(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := source.(*reader.DeviceReader); !ok {
+		t.Errorf("expected a device reader, got %T", source)
+	}
+
+	config.Path = ""
+	if _, err := reader.NewSource(&config); err == nil {
+		t.Error("expected an error without a path")
+	}
+}
@@ -0,0 +1,111 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"errors"
+	"regexp"
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+// A scanner exposing a keyboard, a consumer control and a mouse, as many do
+var (
+	scannerKeyboard = &reader.DeviceInfo{
+		Path: "/dev/input/event3", Name: "Scanner", Phys: "usb-1/input0", Uniq: "SN01",
+		VID: 0x0C2E, PID: 0x0B61, HWID: "VID_0C2E&PID_0B61&NAME_Scanner", Keyboard: true,
+	}
+	scannerConsumer = &reader.DeviceInfo{
+		Path: "/dev/input/event4", Name: "Scanner Consumer Control", Phys: "usb-1/input1", Uniq: "SN01",
+		VID: 0x0C2E, PID: 0x0B61, HWID: "VID_0C2E&PID_0B61&NAME_Scanner Consumer Control",
+	}
+	scannerMouse = &reader.DeviceInfo{
+		Path: "/dev/input/event5", Name: "Scanner Mouse", Phys: "usb-1/input1", Uniq: "SN01",
+		VID: 0x0C2E, PID: 0x0B61, HWID: "VID_0C2E&PID_0B61&NAME_Scanner Mouse",
+	}
+	twinScanner = &reader.DeviceInfo{
+		Path: "/dev/input/event7", Name: "Scanner", Phys: "usb-2/input0", Uniq: "SN02",
+		VID: 0x0C2E, PID: 0x0B61, HWID: "VID_0C2E&PID_0B61&NAME_Scanner", Keyboard: true,
+	}
+	laptopKeyboard = &reader.DeviceInfo{
+		Path: "/dev/input/event0", Name: "AT Translated Set 2 keyboard", Phys: "isa0060/serio0/input0",
+		HWID: "VID_0001&PID_0001&NAME_AT Translated Set 2 keyboard", VID: 0x0001, PID: 0x0001, Keyboard: true,
+	}
+)
+
+func TestDeviceSelectorMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector reader.DeviceSelector
+		matches  bool
+	}{
+		{"vid and pid", reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61}, true},
+		{"vid only", reader.DeviceSelector{VID: 0x0C2E}, true},
+		{"wrong pid", reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B62}, false},
+		{"name", reader.DeviceSelector{Name: "Scanner"}, true},
+		{"name is exact", reader.DeviceSelector{Name: "scanner"}, false},
+		{"phys", reader.DeviceSelector{Phys: "usb-1/input0"}, true},
+		{"uniq", reader.DeviceSelector{Uniq: "SN02"}, false},
+		{"path", reader.DeviceSelector{Path: "/dev/input/event3"}, true},
+		{"hwid regex", reader.DeviceSelector{HWIDRegex: regexp.MustCompile(`PID_0B6\d`)}, true},
+		{"hwid regex mismatch", reader.DeviceSelector{HWIDRegex: regexp.MustCompile(`^PID`)}, false},
+		{"all criteria must match", reader.DeviceSelector{VID: 0x0C2E, Name: "Scanner", Uniq: "SN02"}, false},
+		{"empty", reader.DeviceSelector{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.selector.Match(scannerKeyboard); got != test.matches {
+				t.Errorf("expected %v, got %v", test.matches, got)
+			}
+		})
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	scanner := []*reader.DeviceInfo{laptopKeyboard, scannerKeyboard, scannerConsumer, scannerMouse}
+	twins := append(scanner, twinScanner)
+
+	tests := []struct {
+		name     string
+		selector reader.DeviceSelector
+		devices  []*reader.DeviceInfo
+		selected *reader.DeviceInfo
+		err      error
+	}{
+		{"sibling nodes are skipped", reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61}, scanner, scannerKeyboard, nil},
+		{"identical scanners", reader.DeviceSelector{VID: 0x0C2E, PID: 0x0B61}, twins, nil, reader.ErrAmbiguousDevice},
+		{"told apart by phys", reader.DeviceSelector{VID: 0x0C2E, Phys: "usb-2/input0"}, twins, twinScanner, nil},
+		{"told apart by uniq", reader.DeviceSelector{Name: "Scanner", Uniq: "SN01"}, twins, scannerKeyboard, nil},
+		{"only a non keyboard node matches", reader.DeviceSelector{Name: "Scanner Mouse"}, scanner, nil, reader.ErrDeviceNotFound},
+		{"not connected", reader.DeviceSelector{VID: 0x05E0}, scanner, nil, reader.ErrDeviceNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := reader.SelectDevice(&test.selector, test.devices)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if selected != test.selected {
+				t.Errorf("expected %v, got %v", test.selected, selected)
+			}
+		})
+	}
+}
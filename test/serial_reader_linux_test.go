@@ -0,0 +1,116 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sirafino/go-barcode-relay/reader"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Open a pseudo-terminal pair, returning the master and the path of the slave
+func openPty(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pseudo-terminals not available: %s", err)
+	}
+
+	fd := int(master.Fd())
+
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+
+	index, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+
+	return master, fmt.Sprintf("/dev/pts/%d", index)
+}
+
+func TestSerialReader(t *testing.T) {
+	master, slave := openPty(t)
+	defer master.Close()
+
+	serialReader := reader.SerialReader{
+		DeviceID: "serial",
+		Port:     slave,
+		Baud:     115200,
+		ScanOptions: reader.ScanOptions{
+			Framing: reader.FramingDelimited,
+			Suffix:  "\r\n",
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scans := make(chan reader.Scan, 2)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serialReader.Run(ctx, scans)
+	}()
+
+	// Wait for the port to be opened and put in raw mode before writing,
+	// otherwise the line discipline could echo or translate the input
+	time.Sleep(200 * time.Millisecond)
+
+	// The UTF-8 sequence of é is split across two writes
+	for _, data := range []string{"ABC\r\n12\xc3", "\xa93\r\n"} {
+		if _, err := master.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	for _, expected := range []string{"ABC", "12é3"} {
+		select {
+		case scan := <-scans:
+			if scan.Content != expected {
+				t.Errorf("expected %q, got %q", expected, scan.Content)
+			}
+			if scan.DeviceID != "serial" {
+				t.Errorf("expected device serial, got %s", scan.DeviceID)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+
+	if status := serialReader.Status(); status != reader.StatusConnected {
+		t.Errorf("expected status %s, got %s", reader.StatusConnected, status)
+	}
+
+	cancel()
+	wg.Wait()
+
+	if status := serialReader.Status(); status != reader.StatusStopped {
+		t.Errorf("expected status %s, got %s", reader.StatusStopped, status)
+	}
+}
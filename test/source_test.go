@@ -0,0 +1,78 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"fmt"
+	"sirafino/go-barcode-relay/configuration"
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+func TestNewSource(t *testing.T) {
+	cases := []struct {
+		name   string
+		config configuration.DeviceConfiguration
+		source string
+		valid  bool
+	}{
+		{"default type", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E}, "*reader.DeviceReader", true},
+		{"evdev type", configuration.DeviceConfiguration{ID: "d", Type: "evdev", Name: "scanner"}, "*reader.DeviceReader", true},
+		{"match all", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, MatchAll: true}, "*reader.DeviceSpawner", true},
+		{"negative passthrough gap", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Passthrough: true, PassthroughGapMs: -1}, "", false},
+		{"serial type", configuration.DeviceConfiguration{ID: "d", Type: "serial", Port: "/dev/ttyACM0"}, "*reader.SerialReader", true},
+		{"source alias", configuration.DeviceConfiguration{ID: "d", Source: "serial", Port: "/dev/ttyACM0"}, "*reader.SerialReader", true},
+		{"hidpos type", configuration.DeviceConfiguration{ID: "d", Type: "hidpos", VID: 0x0C2E}, "*reader.HIDPOSReader", true},
+		{"unknown type", configuration.DeviceConfiguration{ID: "d", Type: "bluetooth"}, "", false},
+		{"empty selector", configuration.DeviceConfiguration{ID: "d"}, "", false},
+		{"serial without port", configuration.DeviceConfiguration{ID: "d", Type: "serial"}, "", false},
+		{"serial match all", configuration.DeviceConfiguration{ID: "d", Type: "serial", Port: "/dev/ttyACM0", MatchAll: true}, "", false},
+		{"invalid regex", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, FullScanRegex: "("}, "", false},
+		{"timing", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Timing: &configuration.TimingConfiguration{Action: "flag"}}, "*reader.DeviceReader", true},
+		{"timing serial type", configuration.DeviceConfiguration{ID: "d", Type: "serial", Port: "/dev/ttyACM0", Timing: &configuration.TimingConfiguration{}}, "", false},
+		{"timing with passthrough", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Passthrough: true, Timing: &configuration.TimingConfiguration{}}, "", false},
+		{"timing unknown action", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Timing: &configuration.TimingConfiguration{Action: "block"}}, "", false},
+		{"timing negative interval", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Timing: &configuration.TimingConfiguration{HumanIntervalMs: -1}}, "", false},
+		{"timing tolerance", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Timing: &configuration.TimingConfiguration{Tolerance: 1}}, "", false},
+		{"timing min above human", configuration.DeviceConfiguration{ID: "d", VID: 0x0C2E, Timing: &configuration.TimingConfiguration{MinIntervalMs: 60}}, "", false},
+	}
+
+	for _, c := range cases {
+		source, err := reader.NewSource(&c.config)
+		if (err == nil) != c.valid {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+
+		if !c.valid {
+			continue
+		}
+
+		if source.ID() != c.config.ID {
+			t.Errorf("%s: expected id %s, got %s", c.name, c.config.ID, source.ID())
+		}
+
+		if source.Status() != reader.StatusStarting {
+			t.Errorf("%s: expected status %s, got %s", c.name, reader.StatusStarting, source.Status())
+		}
+
+		if fmt.Sprintf("%T", source) != c.source {
+			t.Errorf("%s: expected a %s, got %T", c.name, c.source, source)
+		}
+	}
+}
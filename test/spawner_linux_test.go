@@ -0,0 +1,171 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"context"
+	"regexp"
+	"sirafino/go-barcode-relay/reader"
+	"slices"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// Scripted devices listed and opened by path, as the spawner does
+type fakeSpawnEvents struct {
+	mutex   sync.Mutex
+	infos   []*reader.DeviceInfo
+	devices map[string]*fakeDevice
+}
+
+func (events *fakeSpawnEvents) Open(selector *reader.DeviceSelector) (reader.EventDevice, error) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	info, err := reader.SelectDevice(selector, events.infos)
+	if err != nil {
+		return nil, err
+	}
+
+	return events.devices[info.Path], nil
+}
+
+func (events *fakeSpawnEvents) Watch(ctx context.Context) <-chan struct{} {
+	return make(chan struct{})
+}
+
+func (events *fakeSpawnEvents) Mirror(device reader.EventDevice) (reader.EventSink, error) {
+	return &fakeSink{}, nil
+}
+
+func (events *fakeSpawnEvents) List(selector *reader.DeviceSelector) ([]*reader.DeviceInfo, error) {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	return reader.MatchingKeyboards(selector, events.infos), nil
+}
+
+func TestDeviceSpawner(t *testing.T) {
+	keyboard := func(path string, phys string, name string) *reader.DeviceInfo {
+		return &reader.DeviceInfo{Path: path, Name: name, Phys: phys, Uniq: "SN", VID: 0x0C2E, PID: 0x0B61, Keyboard: true}
+	}
+
+	// The consumer control node of the first scanner, sharing its phys
+	consumer := &reader.DeviceInfo{Path: "/dev/input/event4", Name: "Scanner Consumer Control", Phys: "usb-1/input0", Uniq: "SN", VID: 0x0C2E, PID: 0x0B61}
+
+	cases := []struct {
+		name       string
+		idTemplate string
+		infos      []*reader.DeviceInfo
+		scans      []string // Device id and content of the expected scans
+	}{
+		{
+			name:  "sibling nodes",
+			infos: []*reader.DeviceInfo{keyboard("/dev/input/event3", "usb-1/input0", "Scanner"), consumer},
+			scans: []string{"scanners-usb-1/input0 /dev/input/event3\n"},
+		},
+		{
+			name: "keyboard nodes sharing their phys",
+			infos: []*reader.DeviceInfo{
+				keyboard("/dev/input/event3", "usb-1/input0", "Scanner"),
+				keyboard("/dev/input/event4", "usb-1/input0", "Scanner Keypad"),
+			},
+			idTemplate: "{{.id}}-{{.name}}",
+			scans: []string{
+				"scanners-Scanner /dev/input/event3\n",
+				"scanners-Scanner Keypad /dev/input/event4\n",
+			},
+		},
+		{
+			name: "no phys",
+			infos: []*reader.DeviceInfo{
+				keyboard("/dev/input/event3", "", "Scanner"),
+				keyboard("/dev/input/event7", "", "Scanner"),
+			},
+			scans: []string{
+				"scanners-/dev/input/event3 /dev/input/event3\n",
+				"scanners-/dev/input/event7 /dev/input/event7\n",
+			},
+		},
+		{
+			name: "duplicate ids",
+			infos: []*reader.DeviceInfo{
+				keyboard("/dev/input/event3", "usb-1/input0", "Scanner"),
+				keyboard("/dev/input/event7", "usb-2/input0", "Scanner"),
+			},
+			idTemplate: "{{.id}}-{{.name}}",
+			scans:      []string{"scanners-Scanner /dev/input/event3\n"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Each device types its own path
+			events := &fakeSpawnEvents{infos: c.infos, devices: map[string]*fakeDevice{}}
+			for _, info := range c.infos {
+				events.devices[info.Path] = newFakeDevice(typeText(info.Path[len("/dev/input/"):]+"\n"), false, nil)
+			}
+
+			idTemplate := c.idTemplate
+			if idTemplate == "" {
+				idTemplate = reader.DefaultIDTemplate
+			}
+
+			spawner := &reader.DeviceSpawner{
+				DeviceID:    "scanners",
+				Selector:    reader.DeviceSelector{VID: 0x0C2E},
+				Layout:      reader.LayoutUS,
+				ScanOptions: reader.ScanOptions{Regex: regexp.MustCompile(`.*?\n`)},
+				IDTemplate:  template.Must(template.New("id").Option("missingkey=error").Parse(idTemplate)),
+				Events:      events,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			scans := make(chan reader.Scan, len(c.infos)+1)
+			stopped := make(chan struct{})
+
+			go func() {
+				defer close(stopped)
+				spawner.Run(ctx, scans)
+			}()
+
+			var got []string
+			timeout := time.After(500 * time.Millisecond)
+
+		collect:
+			for {
+				select {
+				case scan := <-scans:
+					got = append(got, scan.DeviceID+" /dev/input/"+scan.Content)
+				case <-timeout:
+					break collect
+				}
+			}
+
+			cancel()
+			<-stopped
+
+			slices.Sort(got)
+			if !slices.Equal(got, c.scans) {
+				t.Errorf("expected %q, got %q", c.scans, got)
+			}
+		})
+	}
+}
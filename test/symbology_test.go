@@ -0,0 +1,68 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+func TestDetectSymbology(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		content   string
+		symbology string
+		stripped  string
+	}{
+		{"aim ean-13", reader.SymbologyAIM, "]E05901234123457\n", "EAN-13", "5901234123457\n"},
+		{"aim gs1-128", reader.SymbologyAIM, "]C10109501101530003", "GS1-128", "0109501101530003"},
+		{"aim modifier fallback", reader.SymbologyAIM, "]C0ABC-123", "Code 128", "ABC-123"},
+		{"aim unknown code", reader.SymbologyAIM, "]Y0ABC", "", "]Y0ABC"},
+		{"aim missing falls back to guess", reader.SymbologyAIM, "5901234123457", "EAN-13", "5901234123457"},
+		{"aim too short", reader.SymbologyAIM, "]E", "", "]E"},
+		{"honeywell ean-13", reader.SymbologyHoneywell, "d5901234123457\n", "EAN-13", "5901234123457\n"},
+		{"honeywell code 128", reader.SymbologyHoneywell, "jABC", "Code 128", "ABC"},
+		{"honeywell unknown id", reader.SymbologyHoneywell, "#ABC", "", "#ABC"},
+		{"honeywell too short", reader.SymbologyHoneywell, "d", "", "d"},
+		{"detect does not strip", reader.SymbologyDetect, "]E05901234123457", "", "]E05901234123457"},
+		{"guess ean-13", reader.SymbologyDetect, "5901234123457\r\n", "EAN-13", "5901234123457\r\n"},
+		{"guess upc-a", reader.SymbologyDetect, "036000291452", "UPC-A", "036000291452"},
+		{"guess ean-8", reader.SymbologyDetect, "96385074", "EAN-8", "96385074"},
+		{"guess itf-14", reader.SymbologyDetect, "09501101530003", "ITF-14", "09501101530003"},
+		{"bad check digit not guessed", reader.SymbologyDetect, "5901234123458", "", "5901234123458"},
+		{"guess url", reader.SymbologyDetect, "https://example.com/p/1", "QR Code", "https://example.com/p/1"},
+		{"guess gs1", reader.SymbologyDetect, "0109501101530003\x1d10AB12", "GS1", "0109501101530003\x1d10AB12"},
+		{"multiple lines not guessed", reader.SymbologyDetect, "line one\nline two\n", "", "line one\nline two\n"},
+		{"non ascii not guessed", reader.SymbologyDetect, "Größe", "", "Größe"},
+		{"guess empty", reader.SymbologyDetect, "\n", "", "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			symbology, stripped := reader.DetectSymbology(test.content, test.mode)
+
+			if symbology != test.symbology {
+				t.Errorf("expected symbology %q, got %q", test.symbology, symbology)
+			}
+			if stripped != test.stripped {
+				t.Errorf("expected content %q, got %q", test.stripped, stripped)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"slices"
+	"testing"
+	"time"
+)
+
+// The intervals of a scan of n characters typed every step
+func every(step time.Duration, n int) []time.Duration {
+	intervals := make([]time.Duration, n-1)
+	for i := range intervals {
+		intervals[i] = step
+	}
+
+	return intervals
+}
+
+func TestTimingProfile(t *testing.T) {
+	ms := time.Millisecond
+	options := reader.TimingOptions{
+		HumanInterval: 50 * ms,
+		MinInterval:   ms,
+		LearnScans:    3,
+		Tolerance:     3,
+	}
+
+	tests := []struct {
+		name     string
+		options  reader.TimingOptions
+		scans    [][]time.Duration
+		expected []string
+	}{
+		{
+			name:     "scanner",
+			options:  options,
+			scans:    [][]time.Duration{every(8*ms, 13), every(10*ms, 13), every(9*ms, 8), every(8*ms, 13)},
+			expected: []string{"", "", "", ""},
+		},
+		{
+			name:     "human typing",
+			options:  options,
+			scans:    [][]time.Duration{every(150*ms, 6), {20 * ms, 200 * ms, 180 * ms, 90 * ms}},
+			expected: []string{reader.TimingHuman, reader.TimingHuman},
+		},
+		{
+			name:     "a few pauses",
+			options:  options,
+			scans:    [][]time.Duration{{8 * ms, 8 * ms, 300 * ms, 8 * ms, 8 * ms}},
+			expected: []string{""},
+		},
+		{
+			name:     "too fast",
+			options:  options,
+			scans:    [][]time.Duration{every(100*time.Microsecond, 10)},
+			expected: []string{reader.TimingTooFast},
+		},
+		{
+			name:     "too short",
+			options:  options,
+			scans:    [][]time.Duration{every(200*ms, 3)},
+			expected: []string{""},
+		},
+		{
+			name:    "deviation from the profile",
+			options: options,
+			scans: [][]time.Duration{
+				every(4*ms, 10), every(4*ms, 10), every(4*ms, 10),
+				every(20*ms, 10), every(ms+ms/2, 10), every(13*ms, 10),
+			},
+			expected: []string{"", "", "", reader.TimingAnomalous, "", reader.TimingAnomalous},
+		},
+		{
+			name:    "suspicious scans are not learned",
+			options: options,
+			scans: [][]time.Duration{
+				every(100*ms, 10), every(4*ms, 10), every(4*ms, 10), every(4*ms, 10), every(40*ms, 10),
+			},
+			expected: []string{reader.TimingHuman, "", "", "", reader.TimingAnomalous},
+		},
+		{
+			name:     "no profiling",
+			options:  reader.TimingOptions{HumanInterval: 50 * ms, Tolerance: 3},
+			scans:    [][]time.Duration{every(4*ms, 10), every(40*ms, 10)},
+			expected: []string{"", ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile := reader.NewTimingProfile(test.options)
+
+			var got []string
+			for _, intervals := range test.scans {
+				got = append(got, profile.Analyze(intervals))
+			}
+
+			if !slices.Equal(got, test.expected) {
+				t.Errorf("got %q, expected %q", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestSecurityEventScan(t *testing.T) {
+	event := reader.SecurityEvent{
+		DeviceID:  "scanner",
+		Kind:      reader.TimingAnomalous,
+		Content:   "1234\n",
+		Intervals: []time.Duration{120 * time.Millisecond, 1500 * time.Microsecond},
+		Profile:   8 * time.Millisecond,
+		Timestamp: 1700000000,
+	}
+
+	scan := event.Scan()
+
+	if scan.DeviceID != "scanner" || scan.Content != "1234\n" || scan.Timestamp != 1700000000 {
+		t.Errorf("unexpected scan %+v", scan)
+	}
+
+	expected := map[string]string{
+		"event":        "timing_anomalous",
+		"intervals_ms": "120,1.5",
+		"profile_ms":   "8",
+	}
+	for name, value := range expected {
+		if scan.Fields[name] != value {
+			t.Errorf("expected %s %q, got %q", name, value, scan.Fields[name])
+		}
+	}
+}
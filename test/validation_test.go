@@ -0,0 +1,73 @@
+//
+// This file is part of the GoBarcodeRelay distribution (https://github.com/SirAfino/go-barcode-relay).
+// Copyright (c) 2025 Gabriele Serafino.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+//
+
+package test
+
+import (
+	"sirafino/go-barcode-relay/reader"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	cases := []struct {
+		check string
+		code  string
+		valid bool
+	}{
+		{"ean13", "4006381333931\n", true},
+		{"ean13", "4006381333932\n", false},
+		{"ean13", "400638133393\n", false},
+		{"ean8", "96385074", true},
+		{"ean8", "96385075", false},
+		{"upca", "036000291452", true},
+		{"upca", "036000291453", false},
+		{"upce", "04252614", true},
+		{"upce", "04252615", false},
+		{"itf14", "15400141288763", true},
+		{"itf14", "15400141288764", false},
+		{"gtin", "96385074", true},
+		{"gtin", "4006381333931", true},
+		{"gtin", "40063813339", false},
+		{"isbn", "9780306406157", true},
+		{"isbn", "0306406152", true},
+		{"isbn", "0306406153", false},
+		{"code39", "CODE39W", true},
+		{"code39", "*CODE39W*", true},
+		{"code39", "CODE39X", false},
+	}
+
+	for _, c := range cases {
+		validator, err := reader.NewValidator([]string{c.check}, nil, reader.InvalidDrop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scan := reader.Scan{Content: c.code}
+		if validator.Validate(&scan) != c.valid {
+			t.Errorf("%s %q: expected valid=%t", c.check, c.code, c.valid)
+		}
+	}
+
+	validator, _ := reader.NewValidator(nil, []int{4, 6}, reader.InvalidFlag)
+	if !validator.Validate(&reader.Scan{Content: "ABCD\n"}) || validator.Validate(&reader.Scan{Content: "ABCDE\n"}) {
+		t.Errorf("length whitelist not honoured")
+	}
+
+	if _, err := reader.NewValidator([]string{"nope"}, nil, ""); err == nil {
+		t.Errorf("expected an error for an unknown check")
+	}
+}